@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"gonews/db"
@@ -11,9 +12,9 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func IsValid(username, password string, db db.DB) (bool, error) {
+func IsValid(ctx context.Context, username, password string, db db.DB) (bool, error) {
 	var user user.User
-	err := db.Find(&user, clause.Where("username = ?", username))
+	err := db.FindContext(ctx, &user, clause.Where("username = ?", username))
 	if errors.Is(err, query.ErrModelNotFound) {
 		return false, nil
 	} else if err != nil {
@@ -1,6 +1,7 @@
 package auth_test // 'auth_test' instead of 'auth' to prevent gonews/test <- gonews/auth <- gonews/test import cycle
 
 import (
+	"context"
 	"fmt"
 	"gonews/auth"
 	"gonews/db/orm/query"
@@ -25,14 +26,14 @@ func TestIsValidReturnsErrorWhenDatabaseReturnsError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(ptr interface{}, clauses ...*clause.Clause) error {
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
 		_, ok := ptr.(*user.User)
 		assert.True(t, ok)
 
 		return mockErr
 	})
 
-	isValid, err := auth.IsValid(mockUsername, mockPassword, db)
+	isValid, err := auth.IsValid(context.Background(), mockUsername, mockPassword, db)
 	expectedErrMsg := fmt.Sprintf(
 		"failed to get matching user: %v",
 		mockErr.Error())
@@ -44,14 +45,14 @@ func TestIsValidReturnsFalseWhenUserDoesNotExist(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(ptr interface{}, clauses ...*clause.Clause) error {
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
 		_, ok := ptr.(*user.User)
 		assert.True(t, ok)
 
 		return query.ErrModelNotFound
 	})
 
-	isValid, err := auth.IsValid(mockUsername, mockPassword, db)
+	isValid, err := auth.IsValid(context.Background(), mockUsername, mockPassword, db)
 	assert.NoError(t, err)
 	assert.False(t, isValid)
 }
@@ -68,7 +69,7 @@ func TestIsValidReturnsFalseWhenHashDoesNotMatch(t *testing.T) {
 	}
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(ptr interface{}, clauses ...*clause.Clause) error {
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
 		user, ok := ptr.(*user.User)
 		assert.True(t, ok)
 
@@ -77,7 +78,7 @@ func TestIsValidReturnsFalseWhenHashDoesNotMatch(t *testing.T) {
 		return nil
 	})
 
-	isValid, err := auth.IsValid(mockUsername, mockPassword, db)
+	isValid, err := auth.IsValid(context.Background(), mockUsername, mockPassword, db)
 	expectedErrMsg := fmt.Sprintf(
 		"password invalid: %v",
 		bcrypt.ErrMismatchedHashAndPassword)
@@ -97,7 +98,7 @@ func TestIsValidReturnsTrueWhenHashMatches(t *testing.T) {
 	}
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(ptr interface{}, clauses ...*clause.Clause) error {
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
 		user, ok := ptr.(*user.User)
 		assert.True(t, ok)
 
@@ -106,7 +107,7 @@ func TestIsValidReturnsTrueWhenHashMatches(t *testing.T) {
 		return nil
 	})
 
-	isValid, err := auth.IsValid(mockUsername, mockPassword, db)
+	isValid, err := auth.IsValid(context.Background(), mockUsername, mockPassword, db)
 	assert.NoError(t, err)
 	assert.True(t, isValid)
 }
@@ -3,25 +3,47 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"gonews/config"
 	"gonews/db"
+	"gonews/db/orm/query"
 	"gonews/db/orm/query/clause"
 	"gonews/feed"
 	"gonews/lib"
 	"gonews/middleware"
+	"gonews/notify"
+	"gonews/parser"
+	"gonews/websub"
 	"net/http"
 	"os"
 	"path"
 	"strconv"
+	"strings"
 	"text/template"
+	"time"
 
 	"github.com/justinas/nosurf"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// Icons are only refreshed weekly, so they're cached for as long
+const iconMaxAge = 7 * 24 * 60 * 60
+
+// Refreshes run independently of the request, so that they aren't cut short if
+// the client disconnects, but are still bounded
+const refreshTimeout = 10 * time.Minute
+
+// Bounds on the delay before restarting a failed background job, which doubles
+// with each consecutive failure, so that a job which keeps failing doesn't
+// flood the log
+const (
+	minRestartDelay = 5 * time.Second
+	maxRestartDelay = 10 * time.Minute
+)
+
 const (
 	envAuth     = "GONEWS_AUTH"
 	envDebug    = "GONEWS_DEBUG"
@@ -35,7 +57,30 @@ var (
 )
 
 var cfg *config.Config
-var dbCfg *config.DBConfig
+var iconsDir string
+
+// Run the background job forever, restarting it whenever it fails
+// The restart delay is reset once the job has run for longer than the maximum
+// delay, ex. because the DB was only locked temporarily
+func runJob(name string, job func() error) {
+	delay := minRestartDelay
+	for {
+		started := time.Now()
+		err := job()
+		log.Error().Err(err).Msgf("Failed to %s", name)
+
+		if time.Since(started) > maxRestartDelay {
+			delay = minRestartDelay
+		}
+
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+	}
+}
 
 func indexHandlerFunc(w http.ResponseWriter, r *http.Request) {
 	tmplParams := make(map[string]string)
@@ -54,87 +99,307 @@ func indexHandlerFunc(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func itemsHandlerFunc(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
+func itemsHandlerFunc(db db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryParams := r.URL.Query()
 
-	var tagName string
-	tagList, exists := queryParams["tag_name"]
-	if exists {
-		if len(tagList) > 0 {
-			tagName = tagList[0]
+		var tagName string
+		tagList, exists := queryParams["tag_name"]
+		if exists {
+			if len(tagList) > 0 {
+				tagName = tagList[0]
+			}
 		}
-	}
 
-	db, err := db.New(dbCfg)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to create db client")
-		return
-	}
+		var items []*feed.Item
+		var err error
+		if tagName == "" {
+			err = db.AllContext(r.Context(), &items)
+		} else {
+			err = db.FindAllContext(r.Context(), &items, clause.New("where feed_id in (select feed_id from tags where name = ?)", tagName))
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get items")
+			return
+		}
 
-	defer db.Close()
+		err = lib.LoadItemAttachments(r.Context(), db, items)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get item attachments")
+			return
+		}
 
-	var items []*feed.Item
-	if tagName == "" {
-		var items []*feed.Item
-		err = db.All(&items)
-	} else {
-		err = db.FindAll(&items, clause.New("where feed_id in (select feed_id from tags where name = ?)", tagName))
-	}
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to get items")
-		return
-	}
+		err = lib.LoadItemFeedNames(r.Context(), db, items)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get item feed names")
+			return
+		}
 
-	text, err := json.Marshal(&items)
-	if err != nil {
-		fmt.Printf("err: %v\n", err)
+		text, err := json.Marshal(&items)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal json")
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		_, err = w.Write(text)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to render json")
+			return
+		}
 	}
+}
 
-	w.Header().Add("Content-Type", "application/json")
-	_, err = w.Write(text)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to render json")
-		return
+func itemRevisionsHandlerFunc(db db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		itemID, err := strconv.ParseUint(r.URL.Query().Get("item_id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid item_id parameter", http.StatusBadRequest)
+			return
+		}
+
+		diffs, err := lib.ItemRevisions(r.Context(), db, uint(itemID))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get item revisions")
+			return
+		}
+
+		text, err := json.Marshal(&diffs)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal json")
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		_, err = w.Write(text)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to render json")
+			return
+		}
 	}
 }
 
-func hideHandlerFunc(w http.ResponseWriter, r *http.Request) {
-	db, err := db.New(dbCfg)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to create db client")
-		return
+func unhealthyFeedsHandlerFunc(db db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		healths, err := lib.UnhealthyFeeds(r.Context(), db)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get unhealthy feeds")
+			return
+		}
+
+		text, err := json.Marshal(&healths)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal json")
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		_, err = w.Write(text)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to render json")
+			return
+		}
 	}
+}
 
-	defer db.Close()
+func alertsHandlerFunc(db db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryParams := r.URL.Query()
+
+		var limit uint64
+		if queryParams.Get("limit") != "" {
+			var err error
+			limit, err = strconv.ParseUint(queryParams.Get("limit"), 10, 32)
+			if err != nil {
+				http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+		}
 
-	err = r.ParseForm()
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to parse form")
-		return
+		alerts, err := lib.Alerts(r.Context(), db, queryParams.Get("watchlist"), uint(limit))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get alerts")
+			return
+		}
+
+		text, err := json.Marshal(&alerts)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal json")
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		_, err = w.Write(text)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to render json")
+			return
+		}
 	}
+}
 
-	id, err := strconv.ParseUint(r.PostFormValue("ID"), 10, 64)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed parse form ID")
-		return
+// Handles POST /api/v1/feeds/refresh, which fetches every feed immediately,
+// and POST /api/v1/feeds/{id}/refresh, which fetches only the feed with the ID
+func refreshFeedsHandlerFunc(db db.DB, p parser.Parser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var feedID uint64
+		if r.URL.Path != "/api/v1/feeds/refresh" {
+			parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/feeds/"), "/")
+			if len(parts) != 2 || parts[1] != "refresh" {
+				http.NotFound(w, r)
+				return
+			}
+
+			var err error
+			feedID, err = strconv.ParseUint(parts[0], 10, 64)
+			if err != nil || feedID == 0 {
+				http.NotFound(w, r)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		defer cancel()
+
+		summary, err := lib.RefreshFeeds(ctx, cfg, db, p, uint(feedID))
+		if errors.Is(err, lib.ErrFeedNotFound) {
+			http.Error(w, "feed not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Error().Err(err).Msg("Failed to refresh feeds")
+			http.Error(w, "failed to refresh feeds", http.StatusInternalServerError)
+			return
+		}
+
+		log.Info().Msgf("refreshed feeds: %s", summary)
+
+		text, err := json.Marshal(summary)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal json")
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		_, err = w.Write(text)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to render json")
+			return
+		}
 	}
+}
 
-	var item feed.Item
-	err = db.Find(&item, clause.New("where id = ?", uint(id)))
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to get item from ID")
-		return
+// Handles GET /icons/{id}, which serves the icon of the feed with the ID
+func iconHandlerFunc(db db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		feedID, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/icons/"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		var icon feed.Icon
+		err = db.FindContext(r.Context(), &icon, clause.Where("feed_id = ?", uint(feedID)))
+		if errors.Is(err, query.ErrModelNotFound) || (err == nil && icon.ContentType == "") {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			log.Error().Err(err).Msg("Failed to get icon")
+			http.Error(w, "failed to get icon", http.StatusInternalServerError)
+			return
+		}
+
+		f, err := os.Open(lib.IconPath(iconsDir, uint(feedID)))
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			log.Error().Err(err).Msg("Failed to open icon")
+			http.Error(w, "failed to get icon", http.StatusInternalServerError)
+			return
+		}
+
+		defer f.Close()
+
+		// Icons are fetched from feeds' sites, so they're kept from being
+		// interpreted as anything other than images
+		w.Header().Set("Content-Type", icon.ContentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", iconMaxAge))
+		http.ServeContent(w, r, "", icon.FetchedAt, f)
 	}
+}
 
-	item.Hide = true
+func discoverHandlerFunc(p parser.Parser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pageURL := r.URL.Query().Get("url")
+		if pageURL == "" {
+			http.Error(w, "url parameter required", http.StatusBadRequest)
+			return
+		}
 
-	err = db.Save(&item)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to update item")
-		return
+		candidates, err := p.DiscoverPublic(r.Context(), pageURL)
+		if errors.Is(err, parser.ErrUnsupportedScheme) || errors.Is(err, parser.ErrNonPublicAddress) {
+			http.Error(w, "url not allowed", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to discover feeds")
+			http.Error(w, "failed to discover feeds", http.StatusBadGateway)
+			return
+		}
+
+		text, err := json.Marshal(&candidates)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal json")
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		_, err = w.Write(text)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to render json")
+			return
+		}
 	}
+}
+
+func hideHandlerFunc(db db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to parse form")
+			return
+		}
+
+		id, err := strconv.ParseUint(r.PostFormValue("ID"), 10, 64)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed parse form ID")
+			return
+		}
+
+		var item feed.Item
+		err = db.FindContext(r.Context(), &item, clause.New("where id = ?", uint(id)))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get item from ID")
+			return
+		}
 
-	http.Redirect(w, r, r.Referer(), http.StatusSeeOther)
+		item.Hide = true
+
+		err = db.SaveContext(r.Context(), &item)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to update item")
+			return
+		}
+
+		http.Redirect(w, r, r.Referer(), http.StatusSeeOther)
+	}
 }
 
 func main() {
@@ -171,12 +436,13 @@ func main() {
 		return
 	}
 
-	dbCfg = &config.DBConfig{
-		DSN: fmt.Sprintf("file:%s/db.sqlite3", *dataDir),
-	}
-	config.SetDBConfigInst(dbCfg)
+	iconsDir = path.Join(*dataDir, "icons")
+
+	cfg.DB.DSN = fmt.Sprintf("file:%s/db.sqlite3", *dataDir)
 
-	adb, err := db.New(dbCfg)
+	// Shared by the handlers, middleware & background jobs, which use its
+	// connection pool
+	adb, err := db.New(&cfg.DB)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create db client")
 		return
@@ -190,39 +456,87 @@ func main() {
 		return
 	}
 
-	err = lib.InsertMissingFeeds(cfg, adb)
+	err = lib.InsertMissingFeeds(context.Background(), cfg, adb)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to insert new feeds")
 		return
 	}
 
+	p, err := parser.New(cfg)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create feed parser")
+		return
+	}
+
+	authRequired := *authEnabled || os.Getenv(envAuth) == "true"
+
+	// Discovery fetches the given URL from the server's network, so it
+	// requires auth even when the rest of the API doesn't
+	discoverHandler := http.Handler(discoverHandlerFunc(p))
+	if !authRequired {
+		discoverHandler, err = middleware.Wrap(
+			discoverHandler,
+			middleware.NewAuthMiddlewareFunc(adb))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to inject middleware")
+			return
+		}
+	}
+
 	mux := http.NewServeMux()
 	mux.Handle("/", nosurf.New(http.HandlerFunc(indexHandlerFunc)))
-	mux.Handle("/hide", http.HandlerFunc(hideHandlerFunc))
-	mux.Handle("/api/v1/items", http.HandlerFunc(itemsHandlerFunc))
+	mux.Handle("/hide", hideHandlerFunc(adb))
+	mux.Handle("/api/v1/items", itemsHandlerFunc(adb))
+	mux.Handle("/api/v1/items/revisions", itemRevisionsHandlerFunc(adb))
+	mux.Handle("/api/v1/feeds/unhealthy", unhealthyFeedsHandlerFunc(adb))
+	mux.Handle("/api/v1/feeds/discover", discoverHandler)
+	mux.Handle("/api/v1/feeds/refresh", refreshFeedsHandlerFunc(adb, p))
+	mux.Handle("/api/v1/feeds/", refreshFeedsHandlerFunc(adb, p))
+	mux.Handle("/api/v1/alerts", alertsHandlerFunc(adb))
+	mux.Handle("/icons/", iconHandlerFunc(adb))
+
+	go runJob("watch feeds", func() error {
+		return lib.WatchFeeds(context.Background(), cfg, adb)
+	})
+
+	go runJob("auto-dismiss items", func() error {
+		return lib.AutoDismissItems(context.Background(), cfg, adb)
+	})
+
+	go runJob("refresh icons", func() error {
+		return lib.RefreshIcons(context.Background(), cfg, adb, iconsDir)
+	})
+
+	go runJob("purge items", func() error {
+		return lib.PurgeItems(context.Background(), cfg, adb)
+	})
+
+	if cfg.WebSubCallbackURL != "" {
+		go runJob("renew subscriptions", func() error {
+			return lib.RenewSubscriptions(context.Background(), cfg, adb)
+		})
+	}
 
-	go func() {
-		for {
-			err := lib.WatchFeeds(context.Background(), cfg, dbCfg)
-			log.Error().Err(err).Msg("Failed to watch feeds")
+	if len(cfg.Notifiers) > 0 {
+		notifier, err := notify.New(cfg)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create notifier")
+			return
 		}
-	}()
 
-	go func() {
-		for {
-			err := lib.AutoDismissItems(context.Background(), cfg, dbCfg)
-			log.Error().Err(err).Msg("Failed to auto-dismiss items")
-		}
-	}()
+		go runJob("notify alerts", func() error {
+			return lib.NotifyAlerts(context.Background(), adb, notifier)
+		})
+	}
 
 	middlewareFuncs := []middleware.MiddlewareFunc{
 		middleware.LogMiddlewareFunc,
 		middleware.ThrottleMiddlewareFunc,
 	}
-	if *authEnabled || os.Getenv(envAuth) == "true" {
+	if authRequired {
 		middlewareFuncs = append(
 			middlewareFuncs,
-			middleware.AuthMiddlewareFunc)
+			middleware.NewAuthMiddlewareFunc(adb))
 	}
 
 	wrappedHandler, err := middleware.Wrap(mux, middlewareFuncs...)
@@ -231,16 +545,31 @@ func main() {
 		return
 	}
 
+	// Hubs can't authenticate, and deliver content in bursts, so WebSub
+	// callbacks bypass the auth & throttling middleware; requests are
+	// authenticated by their signatures instead
+	websubHandler, err := middleware.Wrap(
+		websub.NewHandler(adb, lib.ReceiveContent(cfg, p)),
+		middleware.LogMiddlewareFunc)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to inject middleware")
+		return
+	}
+
+	rootMux := http.NewServeMux()
+	rootMux.Handle("/websub/", websubHandler)
+	rootMux.Handle("/", wrappedHandler)
+
 	if *tlsEnabled || os.Getenv(envTLS) == "true" {
 		err = http.ListenAndServeTLS(
 			":8080",
 			certPath,
 			keyPath,
-			wrappedHandler)
+			rootMux)
 	} else {
 		err = http.ListenAndServe(
 			":8080",
-			wrappedHandler)
+			rootMux)
 	}
 	log.Error().Err(err).Msg("Server failed")
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -10,13 +11,17 @@ import (
 	"gonews/db"
 	"gonews/db/orm/query/clause"
 	"gonews/feed"
+	"gonews/lib"
 	"gonews/parser"
 	"gonews/timestamp"
 	"gonews/user"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -102,13 +107,64 @@ func saveModels(db db.DB, i interface{}) error {
 	return nil
 }
 
+// Time allowed for the server to finish a fetch requested by -fetch-now, which
+// is longer than the server's own limit on it
+const fetchTimeout = 15 * time.Minute
+
+// Ask the gn server to fetch the feed with the given ID, or every feed if 0,
+// immediately, and return its summary
+// The fetch runs in the server, rather than here, so that it can't overlap
+// with the server's periodic fetches
+func requestFetch(serverURL, credentials string, feedID uint) ([]byte, error) {
+	refreshURL := strings.TrimSuffix(serverURL, "/") + "/api/v1/feeds/refresh"
+	if feedID != 0 {
+		refreshURL = fmt.Sprintf("%s/api/v1/feeds/%d/refresh", strings.TrimSuffix(serverURL, "/"), feedID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, refreshURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if len(credentials) > 0 {
+		res := strings.SplitN(credentials, ":", 2)
+		if len(res) != 2 {
+			return nil, fmt.Errorf("credentials must be of the form 'username:password'")
+		}
+		req.SetBasicAuth(res[0], res[1])
+	}
+
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request fetch: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
+
 func main() {
-	configPath := flag.String("parse-config", "", "parse the application configuration file")
+	configPath := flag.String("parse-config", "", "parse the application configuration file, whose feed options are also used by -parse-url")
 	dbDSN := flag.String("db-dsn", "file:/data/gonews/db.sqlite3", "database DSN")
+	discoverURL := flag.String("discover", "", "show the feeds available from the given website URL")
 	feedID := flag.Uint("items-from-feed", 0, "show items from feed ID")
 	feedURL := flag.String("parse-url", "", "parse items from URL")
+	fetchFeedID := flag.Uint("fetch-feed", 0, "with -fetch-now, only fetch the feed with given ID")
+	fetchNow := flag.Bool("fetch-now", false, "make the gn server at -server-url fetch the feeds immediately, and show how many items were inserted or skipped")
 	hashPassword := flag.String("hash-password", "", "print the hash of the given password")
 	itemID := flag.Uint("item", 0, "show item with given ID")
+	itemRevisionsID := flag.Uint("item-revisions", 0, "show the previous versions of the item with given ID, with their diffs")
 	matchingFeed := flag.String("matching-feed", "", "show matching feed, given serialized feed fields")
 	matchingItem := flag.String("matching-item", "", "show matching item, given serialized item fields")
 	matchingTag := flag.String("matching-tag", "", "show matching tag, given serialized tag fields")
@@ -117,11 +173,16 @@ func main() {
 	migrateDB := flag.Bool("migrate-db", false, "apply DB migrations")
 	migrationsDir := flag.String("migrations-dir", "db/migrations", "database migrations directory")
 	pingDB := flag.Bool("ping-db", false, "ping DB")
+	previewFeed := flag.String("preview-feed", "", "show the items which would be fetched from the configured feed with the given URL, without saving them; requires -parse-config")
+	serverAuth := flag.String("server-auth", "", "authentication credentials for the gn server, if it requires them; ex. 'some_user:some_password'")
+	serverURL := flag.String("server-url", "http://localhost:8080", "gn server URL, used by -fetch-now")
 	showFeeds := flag.Bool("feeds", false, "show feeds")
 	showItems := flag.Bool("items", false, "show items")
 	showTags := flag.Bool("tags", false, "show tags")
+	showUnhealthyFeeds := flag.Bool("unhealthy-feeds", false, "show feeds whose most recent fetch failed, with their statuses")
 	showUsers := flag.Bool("users", false, "show users")
 	tagName := flag.String("items-from-tag", "", "show items from tag name")
+	testRules := flag.String("test-rules", "", "show the stored items of the feed with the given URL which its filter rules, and those of its tags, would drop, hide or keep; requires -parse-config")
 	testAuth := flag.String("test-auth", "", "validate the given authentication credentials; ex. 'some_user:some_password'")
 	upsertFeeds := flag.Bool("upsert-feeds", false, "upsert the given serialized feeds read from stdin, one per line")
 	upsertItems := flag.Bool("upsert-items", false, "upsert the given serialized items read from stdin, one per line")
@@ -138,11 +199,13 @@ func main() {
 		return
 	}
 
+	// The parsed config, if any, is also used to configure the parser
+	var parsedConfig *config.Config
 	if len(*configPath) > 0 {
 		dir := path.Dir(*configPath)
 		base := path.Base(*configPath)
 		name := strings.Replace(base, path.Ext(base), "", 1)
-		parsedConfig, err := config.New(dir, name)
+		parsedConfig, err = config.New(dir, name)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to parse application configuration file")
 			return
@@ -198,6 +261,34 @@ func main() {
 		}
 	}
 
+	if *itemRevisionsID != 0 {
+		diffs, err := lib.ItemRevisions(context.Background(), adb, *itemRevisionsID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get item revisions")
+			return
+		}
+
+		err = printModels(diffs)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to print item revisions")
+			return
+		}
+	}
+
+	if *showUnhealthyFeeds {
+		healths, err := lib.UnhealthyFeeds(context.Background(), adb)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get unhealthy feeds")
+			return
+		}
+
+		err = printModels(healths)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to print unhealthy feeds")
+			return
+		}
+	}
+
 	if *showTags {
 		var tags []*feed.Tag
 		err := adb.All(&tags)
@@ -287,7 +378,7 @@ func main() {
 		res := strings.Split(*testAuth, ":")
 		username := res[0]
 		password := res[1]
-		isValid, err := auth.IsValid(username, password, adb)
+		isValid, err := auth.IsValid(context.Background(), username, password, adb)
 
 		// TODO: fix so that it can distinguish between an actual error and an 'invalid creds' error
 		if err != nil {
@@ -404,13 +495,13 @@ func main() {
 	}
 
 	if len(*feedURL) > 0 {
-		p, err := parser.New()
+		p, err := parser.New(parsedConfig)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to create parser")
 			return
 		}
 
-		items, err := p.ParseURL(*feedURL)
+		items, err := p.ParseURL(context.Background(), *feedURL)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to parse feed")
 			return
@@ -423,6 +514,89 @@ func main() {
 		}
 	}
 
+	if len(*previewFeed) > 0 {
+		if parsedConfig == nil {
+			log.Error().Msg("Previewing a feed requires -parse-config")
+			return
+		}
+
+		var found bool
+		for _, fc := range parsedConfig.Feeds {
+			found = found || fc.URL == *previewFeed
+		}
+		if !found {
+			log.Error().Msgf("Feed not found in configuration: %s", *previewFeed)
+			return
+		}
+
+		p, err := parser.New(parsedConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create parser")
+			return
+		}
+
+		items, err := p.ParseURL(context.Background(), *previewFeed)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to parse feed")
+			return
+		}
+
+		err = printModels(items)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to print items")
+			return
+		}
+	}
+
+	if len(*testRules) > 0 {
+		if parsedConfig == nil {
+			log.Error().Msg("Testing rules requires -parse-config")
+			return
+		}
+
+		matches, err := lib.TestRules(context.Background(), parsedConfig, adb, *testRules)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to test rules")
+			return
+		}
+
+		err = printModels(matches)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to print rule matches")
+			return
+		}
+	}
+
+	if *fetchNow {
+		summary, err := requestFetch(*serverURL, *serverAuth, *fetchFeedID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to fetch feeds")
+			return
+		}
+
+		fmt.Println(string(summary))
+	}
+
+	if len(*discoverURL) > 0 {
+		p, err := parser.New(parsedConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create parser")
+			return
+		}
+
+		candidates, err := p.Discover(context.Background(), *discoverURL)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to discover feeds")
+			return
+		}
+
+		err = printModels(candidates)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to print feeds")
+			return
+		}
+	}
+
 	if *upsertTimestamps {
 		var timestamps []*timestamp.Timestamp
 		err := scanModels(&timestamps)
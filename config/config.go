@@ -2,47 +2,174 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
-var (
-	dbConfigInst *DBConfig
-)
-
 // Config contains the values parsed from the config file
 type Config struct {
-	AppTitle          string `mapstructure:"homepage_title"`
-	Feeds             []*FeedConfig
-	FetchPeriod       time.Duration `mapstructure:"feed_fetch_period"`
-	AutoDismissPeriod time.Duration `mapstructure:"auto_dismiss_period"`
+	AppTitle            string `mapstructure:"homepage_title"`
+	Feeds               []*FeedConfig
+	FetchPeriod         time.Duration `mapstructure:"feed_fetch_period"`
+	FetchWorkers        uint          `mapstructure:"fetch_workers"`
+	FetchWorkersPerHost uint          `mapstructure:"fetch_workers_per_host"`
+	AutoDismissPeriod   time.Duration `mapstructure:"auto_dismiss_period"`
+	UnhideUpdatedItems  bool          `mapstructure:"unhide_updated_items"`
+	WebSubCallbackURL   string        `mapstructure:"websub_callback_url"`
+	WebSubLease         time.Duration `mapstructure:"websub_lease"`
+	SecretsFile         string        `mapstructure:"secrets_file"`
+	Tags                map[string]*TagConfig
+	Watchlists          []*WatchlistConfig
+	Notifiers           []*NotifierConfig
+	Retention           *RetentionConfig
+	RetentionPeriod     time.Duration `mapstructure:"retention_period"`
+	DB                  DBConfig      `mapstructure:"db"`
+}
+
+// RetentionConfig contains the policy for deleting a feed's old items
+// Hidden items are deleted once they're older than HiddenAfter, and only the
+// newest MaxItems items are kept; zero values keep items forever
+type RetentionConfig struct {
+	HiddenAfter time.Duration `mapstructure:"hidden_after"`
+	MaxItems    uint          `mapstructure:"max_items"`
+}
+
+// Actions of filter rules
+const (
+	RuleDrop = "drop"
+	RuleHide = "hide"
+	RuleKeep = "keep"
+)
+
+// RuleFields are the item fields which filter rules can match
+var RuleFields = []string{"title", "description", "author", "link"}
+
+// RuleConfig contains a filter rule for the new items of a feed
+// The rule matches items whose fields contain any of the keywords, case
+// insensitively, or match the regex; Fields defaults to all of title,
+// description, author & link, and a rule without keywords or a regex matches
+// every item
+// Action is what's done with matching items: drop them, hide them when
+// they're inserted, or keep them, skipping any later rules
+type RuleConfig struct {
+	Action   string
+	Fields   []string
+	Keywords []string
+	Regex    string
+}
+
+// TagConfig contains the values associated with each tag, parsed from the
+// config file; the rules apply to the feeds with the tag, after their own
+type TagConfig struct {
+	Rules []*RuleConfig
+}
+
+// WatchlistConfig contains a watchlist, which raises an alert for each new
+// item whose title, description or content contains any of the terms, case
+// insensitively, or matches any of the regexes
+// If Tags is set, only the items of feeds with one of the tags are matched
+type WatchlistConfig struct {
+	Name    string
+	Terms   []string
+	Regexes []string
+	Tags    []string
+}
+
+// Types of alert notifiers
+const (
+	NotifierLog     = "log"
+	NotifierWebhook = "webhook"
+)
+
+// NotifierConfig contains a notifier, which alerts are sent to as they're
+// raised; webhook notifiers POST each alert as JSON to the URL
+type NotifierConfig struct {
+	Type string
+	URL  string
 }
 
 // FeedConfig contains the values associated with each feed, parsed from the
 // config file
+// Headers, UserAgent, BasicAuth, Timeout & MaxBodyBytes configure the HTTP
+// requests for the feed, ex. to authenticate to private feeds; BasicAuth names
+// the credentials in the secrets file, which are set in Credentials
+// If Command is set, the feed is read from the output of the command, run with
+// the given arguments, instead of from the URL; the URL still identifies the
+// feed and defaults to a command: URL
+// Likewise, if Maildir is set, the feed's items are the new messages in the
+// Maildir directory, and the URL defaults to a maildir: URL
+// If Scrape is set, the feed is an HTML page which items are scraped from
+// Name, if set, is shown instead of the feed's title
+// Retention overrides the non-zero values of the global retention policy
 type FeedConfig struct {
 	URL              string
+	Name             string
 	Tags             []string
-	FetchLimit       uint          `mapstructure:"fetch_limit"`
-	AutoDismissAfter time.Duration `mapstructure:"auto_dismiss_after"`
+	FetchLimit       uint              `mapstructure:"fetch_limit"`
+	FetchPeriod      time.Duration     `mapstructure:"fetch_period"`
+	FullText         bool              `mapstructure:"full_text"`
+	AutoDismissAfter time.Duration     `mapstructure:"auto_dismiss_after"`
+	Headers          map[string]string `mapstructure:"headers"`
+	UserAgent        string            `mapstructure:"user_agent"`
+	BasicAuth        string            `mapstructure:"basic_auth"`
+	Timeout          time.Duration     `mapstructure:"timeout"`
+	MaxBodyBytes     int64             `mapstructure:"max_body_bytes"`
+	Command          []string          `mapstructure:"command"`
+	Maildir          string            `mapstructure:"maildir"`
+	Scrape           *ScrapeConfig     `mapstructure:"scrape"`
+	Rules            []*RuleConfig     `mapstructure:"rules"`
+	Retention        *RetentionConfig  `mapstructure:"retention"`
+	Credentials      *Credentials      `mapstructure:"-"`
 }
 
-// DBConfig contains the values needed to connect to the database
-// Note: currently not parsed from the config file
-type DBConfig struct {
-	DSN string
+// ScrapeConfig contains the CSS selectors used to scrape items from an HTML
+// page
+// Item selects the element containing each item, and the other selectors
+// select the item's fields within it; the link is the href of the selected
+// element or its first link, and the date is parsed with DateLayout, in the
+// format of time.Parse
+type ScrapeConfig struct {
+	Item        string
+	Title       string
+	Link        string
+	Date        string
+	DateLayout  string `mapstructure:"date_layout"`
+	Description string
 }
 
-// SetDBConfigInst assigns the global database configuration instance to the
-// given config
-func SetDBConfigInst(dbCfg *DBConfig) {
-	dbConfigInst = dbCfg
+// Credentials contains a username & password for HTTP basic authentication
+type Credentials struct {
+	Username string
+	Password string
 }
 
-// DBConfigInst returns the global database configuration instance
-func DBConfigInst() *DBConfig {
-	return dbConfigInst
+// Secrets contains the values parsed from the secrets file, which is kept
+// separate from the config file so that it can have stricter permissions
+// BasicAuth maps names, referenced by the feeds' basic_auth values, to
+// credentials
+type Secrets struct {
+	BasicAuth map[string]*Credentials `mapstructure:"basic_auth"`
+}
+
+// Journal modes of the database
+var JournalModes = []string{"delete", "truncate", "persist", "memory", "wal", "off"}
+
+// DBConfig contains the values needed to connect to the database
+// The DSN isn't parsed from the config file, since it's set from the data
+// directory; zero values keep the defaults of the driver & database/sql
+type DBConfig struct {
+	DSN             string        `mapstructure:"-"`
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	BusyTimeout     time.Duration `mapstructure:"busy_timeout"`
+	JournalMode     string        `mapstructure:"journal_mode"`
+	ForeignKeys     bool          `mapstructure:"foreign_keys"`
 }
 
 // New creates an instance of Config by parsing the given config file
@@ -61,23 +188,404 @@ func New(path, name string) (*Config, error) {
 		return &c, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	c.setSourceURLs()
+
+	err = c.setCredentials(path)
+	if err != nil {
+		return &c, err
+	}
+
+	err = c.validateRules()
+	if err != nil {
+		return &c, err
+	}
+
+	err = c.validateWatchlists()
+	if err != nil {
+		return &c, err
+	}
+
+	err = c.validateNotifiers()
+	if err != nil {
+		return &c, err
+	}
+
+	err = c.DB.validate()
+	if err != nil {
+		return &c, err
+	}
+
 	return &c, nil
 }
 
+func (rc *RuleConfig) validate() error {
+	switch rc.Action {
+	case RuleDrop, RuleHide, RuleKeep:
+	default:
+		return fmt.Errorf("invalid rule action %q", rc.Action)
+	}
+
+	for _, field := range rc.Fields {
+		valid := false
+		for _, ruleField := range RuleFields {
+			valid = valid || field == ruleField
+		}
+		if !valid {
+			return fmt.Errorf("invalid rule field %q", field)
+		}
+	}
+
+	if rc.Regex != "" {
+		_, err := regexp.Compile(rc.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid rule regex: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate the filter rules of the feeds & tags, so that invalid rules are
+// reported when the config is read rather than when feeds are fetched
+func (c *Config) validateRules() error {
+	for _, fc := range c.Feeds {
+		for _, rc := range fc.Rules {
+			err := rc.validate()
+			if err != nil {
+				return fmt.Errorf("failed to validate rule for feed %s: %w", fc.URL, err)
+			}
+		}
+	}
+
+	for name, tc := range c.Tags {
+		for _, rc := range tc.Rules {
+			err := rc.validate()
+			if err != nil {
+				return fmt.Errorf("failed to validate rule for tag %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// FeedRules returns the filter rules of the feed with the given URL, followed
+// by those of its tags, in the order they're applied
+func (c *Config) FeedRules(feedURL string) []*RuleConfig {
+	var rules []*RuleConfig
+	for _, fc := range c.Feeds {
+		if fc.URL != feedURL {
+			continue
+		}
+
+		rules = append(rules, fc.Rules...)
+
+		// Tag names are case insensitive
+		for _, tag := range fc.Tags {
+			if tc, ok := c.Tags[strings.ToLower(tag)]; ok {
+				rules = append(rules, tc.Rules...)
+			}
+		}
+	}
+
+	return rules
+}
+
+func (wc *WatchlistConfig) validate() error {
+	if wc.Name == "" {
+		return fmt.Errorf("watchlist name required")
+	}
+
+	if len(wc.Terms) == 0 && len(wc.Regexes) == 0 {
+		return fmt.Errorf("watchlist %s has no terms or regexes", wc.Name)
+	}
+
+	for _, regex := range wc.Regexes {
+		_, err := regexp.Compile(regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex for watchlist %s: %w", wc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate the watchlists, whose names must be unique, since alerts refer to
+// them by name
+func (c *Config) validateWatchlists() error {
+	names := make(map[string]bool)
+	for _, wc := range c.Watchlists {
+		err := wc.validate()
+		if err != nil {
+			return fmt.Errorf("failed to validate watchlist: %w", err)
+		}
+
+		if names[wc.Name] {
+			return fmt.Errorf("duplicate watchlist %s", wc.Name)
+		}
+		names[wc.Name] = true
+	}
+
+	return nil
+}
+
+func (c *Config) validateNotifiers() error {
+	for _, nc := range c.Notifiers {
+		switch nc.Type {
+		case NotifierLog:
+		case NotifierWebhook:
+			if nc.URL == "" {
+				return fmt.Errorf("webhook notifier URL required")
+			}
+		default:
+			return fmt.Errorf("invalid notifier type %q", nc.Type)
+		}
+	}
+
+	return nil
+}
+
+func (dc *DBConfig) validate() error {
+	if dc.MaxOpenConns < 0 || dc.MaxIdleConns < 0 {
+		return fmt.Errorf("invalid db connection limits")
+	}
+
+	if dc.JournalMode == "" {
+		return nil
+	}
+
+	for _, mode := range JournalModes {
+		if strings.ToLower(dc.JournalMode) == mode {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid db journal mode %q", dc.JournalMode)
+}
+
+// FeedWatchlists returns the watchlists which apply to the feed with the given
+// URL: those without tags, and those sharing a tag with the feed
+func (c *Config) FeedWatchlists(feedURL string) []*WatchlistConfig {
+	// Tag names are case insensitive
+	feedTags := make(map[string]bool)
+	for _, fc := range c.Feeds {
+		if fc.URL != feedURL {
+			continue
+		}
+
+		for _, tag := range fc.Tags {
+			feedTags[strings.ToLower(tag)] = true
+		}
+	}
+
+	var watchlists []*WatchlistConfig
+	for _, wc := range c.Watchlists {
+		matches := len(wc.Tags) == 0
+		for _, tag := range wc.Tags {
+			matches = matches || feedTags[strings.ToLower(tag)]
+		}
+
+		if matches {
+			watchlists = append(watchlists, wc)
+		}
+	}
+
+	return watchlists
+}
+
+// FeedRetention returns the retention policy of the feed with the given URL:
+// the global policy, with any values set by the feed overriding it
+func (c *Config) FeedRetention(feedURL string) RetentionConfig {
+	var rc RetentionConfig
+	if c.Retention != nil {
+		rc = *c.Retention
+	}
+
+	for _, fc := range c.Feeds {
+		if fc.URL != feedURL || fc.Retention == nil {
+			continue
+		}
+
+		if fc.Retention.HiddenAfter != 0 {
+			rc.HiddenAfter = fc.Retention.HiddenAfter
+		}
+		if fc.Retention.MaxItems != 0 {
+			rc.MaxItems = fc.Retention.MaxItems
+		}
+	}
+
+	return rc
+}
+
+// Set the URL of the command & Maildir feeds which don't have one, ex.
+// 'command:/usr/local/bin/feed --all' for ["/usr/local/bin/feed", "--all"]
+func (c *Config) setSourceURLs() {
+	for _, fc := range c.Feeds {
+		if fc.URL != "" {
+			continue
+		}
+
+		if len(fc.Command) > 0 {
+			fc.URL = "command:" + strings.Join(fc.Command, " ")
+		} else if fc.Maildir != "" {
+			fc.URL = "maildir:" + fc.Maildir
+		}
+	}
+}
+
+// Read the secrets file at the given path
+func readSecrets(path string) (*Secrets, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("toml")
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets: %w", err)
+	}
+
+	var s Secrets
+	err = v.Unmarshal(&s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal secrets: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Set the credentials of the feeds which use basic authentication from the
+// secrets file, whose path is relative to the config directory
+func (c *Config) setCredentials(configDir string) error {
+	secrets := &Secrets{}
+	if c.SecretsFile != "" {
+		secretsPath := c.SecretsFile
+		if !filepath.IsAbs(secretsPath) {
+			secretsPath = filepath.Join(configDir, secretsPath)
+		}
+
+		var err error
+		secrets, err = readSecrets(secretsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, fc := range c.Feeds {
+		if fc.BasicAuth == "" {
+			continue
+		}
+
+		// Keys are case insensitive
+		credentials, ok := secrets.BasicAuth[strings.ToLower(fc.BasicAuth)]
+		if !ok {
+			return fmt.Errorf("failed to find basic auth credentials %q for feed %s", fc.BasicAuth, fc.URL)
+		}
+
+		fc.Credentials = credentials
+	}
+
+	return nil
+}
+
 func (c Config) String() string {
 	return fmt.Sprintf(
-		"App Title: %s, Feeds: %s, Fetch Period: %s, AutoDismissPeriod: %s",
+		"App Title: %s, Feeds: %s, Fetch Period: %s, Fetch Workers: %d, Fetch Workers Per Host: %d, AutoDismissPeriod: %s, Unhide Updated Items: %t, WebSub Callback URL: %s, WebSub Lease: %s, Secrets File: %s, Tags: %s, Watchlists: %s, Notifiers: %s, Retention: %s, Retention Period: %s, DB: %s",
 		c.AppTitle,
 		c.Feeds,
 		c.FetchPeriod,
-		c.AutoDismissPeriod)
+		c.FetchWorkers,
+		c.FetchWorkersPerHost,
+		c.AutoDismissPeriod,
+		c.UnhideUpdatedItems,
+		c.WebSubCallbackURL,
+		c.WebSubLease,
+		c.SecretsFile,
+		c.Tags,
+		c.Watchlists,
+		c.Notifiers,
+		c.Retention,
+		c.RetentionPeriod,
+		c.DB)
 }
 
+// Header values & credentials are omitted, since they may be secret
 func (fc FeedConfig) String() string {
+	var headers []string
+	for name := range fc.Headers {
+		headers = append(headers, name)
+	}
+	sort.Strings(headers)
+
 	return fmt.Sprintf(
-		"URL: %s, Tags: %s, Fetch Limit: %d, AutoDismissAfter: %s",
+		"URL: %s, Name: %s, Tags: %s, Fetch Limit: %d, Fetch Period: %s, Full Text: %t, AutoDismissAfter: %s, Headers: %s, User Agent: %s, Basic Auth: %s, Timeout: %s, Max Body Bytes: %d, Command: %s, Maildir: %s, Scrape: %s, Rules: %s, Retention: %s",
 		fc.URL,
+		fc.Name,
 		fc.Tags,
 		fc.FetchLimit,
-		fc.AutoDismissAfter)
+		fc.FetchPeriod,
+		fc.FullText,
+		fc.AutoDismissAfter,
+		headers,
+		fc.UserAgent,
+		fc.BasicAuth,
+		fc.Timeout,
+		fc.MaxBodyBytes,
+		fc.Command,
+		fc.Maildir,
+		fc.Scrape,
+		fc.Rules,
+		fc.Retention)
+}
+
+func (sc ScrapeConfig) String() string {
+	return fmt.Sprintf(
+		"Item: %s, Title: %s, Link: %s, Date: %s, Date Layout: %s, Description: %s",
+		sc.Item,
+		sc.Title,
+		sc.Link,
+		sc.Date,
+		sc.DateLayout,
+		sc.Description)
+}
+
+func (tc TagConfig) String() string {
+	return fmt.Sprintf("Rules: %s", tc.Rules)
+}
+
+func (rc RuleConfig) String() string {
+	return fmt.Sprintf(
+		"Action: %s, Fields: %s, Keywords: %s, Regex: %s",
+		rc.Action,
+		rc.Fields,
+		rc.Keywords,
+		rc.Regex)
+}
+
+func (wc WatchlistConfig) String() string {
+	return fmt.Sprintf(
+		"Name: %s, Terms: %s, Regexes: %s, Tags: %s",
+		wc.Name,
+		wc.Terms,
+		wc.Regexes,
+		wc.Tags)
+}
+
+// The URL is omitted, since webhook URLs often contain tokens
+func (nc NotifierConfig) String() string {
+	return fmt.Sprintf("Type: %s", nc.Type)
+}
+
+func (rc RetentionConfig) String() string {
+	return fmt.Sprintf("Hidden After: %s, Max Items: %d", rc.HiddenAfter, rc.MaxItems)
+}
+
+// The DSN is omitted, since it may contain credentials
+func (dc DBConfig) String() string {
+	return fmt.Sprintf(
+		"Max Open Conns: %d, Max Idle Conns: %d, Conn Max Lifetime: %s, Busy Timeout: %s, Journal Mode: %s, Foreign Keys: %t",
+		dc.MaxOpenConns,
+		dc.MaxIdleConns,
+		dc.ConnMaxLifetime,
+		dc.BusyTimeout,
+		dc.JournalMode,
+		dc.ForeignKeys)
 }
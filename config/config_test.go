@@ -0,0 +1,316 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600)
+	assert.NoError(t, err)
+}
+
+func TestNewSetsFeedCredentialsFromSecretsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config.toml", `
+secrets_file = "secrets.toml"
+
+[[feeds]]
+url = "https://jira.example.com/activity"
+basic_auth = "Jira"
+headers = { Cookie = "session=abc" }
+user_agent = "custom-agent"
+timeout = "10s"
+max_body_bytes = 1024
+
+[[feeds]]
+url = "https://example.com/feed"
+`)
+	writeFile(t, dir, "secrets.toml", `
+[basic_auth.jira]
+username = "user"
+password = "secret"
+`)
+
+	cfg, err := New(dir, "config")
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Feeds, 2)
+
+	fc := cfg.Feeds[0]
+	assert.Equal(t, &Credentials{Username: "user", Password: "secret"}, fc.Credentials)
+	assert.Equal(t, "session=abc", fc.Headers["Cookie"])
+	assert.Equal(t, "custom-agent", fc.UserAgent)
+	assert.Equal(t, 10*time.Second, fc.Timeout)
+	assert.Equal(t, int64(1024), fc.MaxBodyBytes)
+	assert.NotContains(t, fc.String(), "secret")
+	assert.NotContains(t, fc.String(), "session=abc")
+
+	assert.Nil(t, cfg.Feeds[1].Credentials)
+}
+
+func TestNewReturnsErrorWhenCredentialsAreMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config.toml", `
+[[feeds]]
+url = "https://jira.example.com/activity"
+basic_auth = "jira"
+`)
+
+	_, err = New(dir, "config")
+	assert.Error(t, err)
+}
+
+func TestNewSetsURLOfCommandAndMaildirFeeds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config.toml", `
+[[feeds]]
+command = ["/usr/local/bin/feed", "--all"]
+
+[[feeds]]
+url = "https://example.com/"
+command = ["/usr/local/bin/feed"]
+
+[[feeds]]
+maildir = "/var/mail/newsletters"
+`)
+
+	cfg, err := New(dir, "config")
+	assert.NoError(t, err)
+	assert.Equal(t, "command:/usr/local/bin/feed --all", cfg.Feeds[0].URL)
+	assert.Equal(t, []string{"/usr/local/bin/feed", "--all"}, cfg.Feeds[0].Command)
+	assert.Equal(t, "https://example.com/", cfg.Feeds[1].URL)
+	assert.Equal(t, "maildir:/var/mail/newsletters", cfg.Feeds[2].URL)
+}
+
+func TestNewParsesScrapeConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config.toml", `
+[[feeds]]
+url = "https://example.com/advisories"
+
+[feeds.scrape]
+item = "table tr"
+title = "td.title"
+date = "td.date"
+date_layout = "2006-01-02"
+`)
+
+	cfg, err := New(dir, "config")
+	assert.NoError(t, err)
+	assert.Equal(t, &ScrapeConfig{
+		Item:       "table tr",
+		Title:      "td.title",
+		Date:       "td.date",
+		DateLayout: "2006-01-02",
+	}, cfg.Feeds[0].Scrape)
+}
+
+func TestNewParsesRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config.toml", `
+[[feeds]]
+url = "https://news.ycombinator.com/rss"
+tags = ["Tech"]
+
+[[feeds.rules]]
+action = "drop"
+fields = ["title"]
+regex = "^(Show|Ask) HN"
+
+[[tags.tech.rules]]
+action = "hide"
+keywords = ["bitcoin", "nft"]
+`)
+
+	cfg, err := New(dir, "config")
+	assert.NoError(t, err)
+	assert.Equal(t, []*RuleConfig{
+		{Action: RuleDrop, Fields: []string{"title"}, Regex: "^(Show|Ask) HN"},
+		{Action: RuleHide, Keywords: []string{"bitcoin", "nft"}},
+	}, cfg.FeedRules("https://news.ycombinator.com/rss"))
+	assert.Empty(t, cfg.FeedRules("https://example.com/"))
+}
+
+func TestNewReturnsErrorOnInvalidRule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, rule := range []string{
+		`action = "delete"`,
+		`action = "drop"` + "\n" + `fields = ["body"]`,
+		`action = "drop"` + "\n" + `regex = "("`,
+	} {
+		writeFile(t, dir, "config.toml", `
+[[feeds]]
+url = "https://example.com/"
+
+[[feeds.rules]]
+`+rule+"\n")
+
+		_, err = New(dir, "config")
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestNewParsesWatchlists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config.toml", `
+[[feeds]]
+url = "https://news.ycombinator.com/rss"
+tags = ["Tech"]
+
+[[feeds]]
+url = "https://example.com/"
+
+[[watchlists]]
+name = "products"
+terms = ["gonews"]
+
+[[watchlists]]
+name = "vulnerabilities"
+regexes = ["CVE-\\d+-\\d+"]
+tags = ["tech"]
+
+[[notifiers]]
+type = "webhook"
+url = "https://hooks.example.com/alerts"
+`)
+
+	cfg, err := New(dir, "config")
+	assert.NoError(t, err)
+	assert.Equal(t, []*WatchlistConfig{
+		{Name: "products", Terms: []string{"gonews"}},
+		{Name: "vulnerabilities", Regexes: []string{`CVE-\d+-\d+`}, Tags: []string{"tech"}},
+	}, cfg.FeedWatchlists("https://news.ycombinator.com/rss"))
+	assert.Equal(t, []*WatchlistConfig{
+		{Name: "products", Terms: []string{"gonews"}},
+	}, cfg.FeedWatchlists("https://example.com/"))
+	assert.Equal(t, []*NotifierConfig{
+		{Type: NotifierWebhook, URL: "https://hooks.example.com/alerts"},
+	}, cfg.Notifiers)
+}
+
+func TestNewReturnsErrorOnInvalidWatchlist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, watchlist := range []string{
+		`terms = ["gonews"]`,
+		`name = "empty"`,
+		`name = "invalid"` + "\n" + `regexes = ["("]`,
+		`name = "dup"` + "\n" + `terms = ["a"]` + "\n[[watchlists]]\n" + `name = "dup"` + "\n" + `terms = ["b"]`,
+	} {
+		writeFile(t, dir, "config.toml", "[[watchlists]]\n"+watchlist+"\n")
+
+		_, err = New(dir, "config")
+		assert.Error(t, err, watchlist)
+	}
+
+	writeFile(t, dir, "config.toml", `
+[[notifiers]]
+type = "email"
+`)
+	_, err = New(dir, "config")
+	assert.Error(t, err)
+}
+
+func TestNewParsesRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config.toml", `
+retention_period = "6h"
+
+[retention]
+hidden_after = "720h"
+max_items = 500
+
+[[feeds]]
+url = "https://news.ycombinator.com/rss"
+
+[feeds.retention]
+max_items = 100
+
+[[feeds]]
+url = "https://example.com/"
+`)
+
+	cfg, err := New(dir, "config")
+	assert.NoError(t, err)
+	assert.Equal(t, 6*time.Hour, cfg.RetentionPeriod)
+	assert.Equal(t, RetentionConfig{
+		HiddenAfter: 720 * time.Hour,
+		MaxItems:    100,
+	}, cfg.FeedRetention("https://news.ycombinator.com/rss"))
+	assert.Equal(t, RetentionConfig{
+		HiddenAfter: 720 * time.Hour,
+		MaxItems:    500,
+	}, cfg.FeedRetention("https://example.com/"))
+}
+
+func TestNewParsesDBConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config.toml", `
+[db]
+max_open_conns = 8
+max_idle_conns = 4
+conn_max_lifetime = "1h"
+busy_timeout = "10s"
+journal_mode = "wal"
+foreign_keys = true
+`)
+
+	cfg, err := New(dir, "config")
+	assert.NoError(t, err)
+	assert.Equal(t, DBConfig{
+		MaxOpenConns:    8,
+		MaxIdleConns:    4,
+		ConnMaxLifetime: time.Hour,
+		BusyTimeout:     10 * time.Second,
+		JournalMode:     "wal",
+		ForeignKeys:     true,
+	}, cfg.DB)
+}
+
+func TestNewReturnsErrorOnInvalidJournalMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config.toml", `
+[db]
+journal_mode = "fast"
+`)
+
+	_, err = New(dir, "config")
+	assert.Error(t, err)
+}
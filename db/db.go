@@ -1,36 +1,108 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"gonews/config"
+	_ "gonews/db/migrations" // registers the Go migrations
 	"gonews/db/orm/client"
 	"gonews/db/orm/query/clause"
 	"os"
+	"strconv"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/pressly/goose"
 )
 
+// Tx contains the methods needed to store and read data, either in a
+// transaction or, as part of DB, in a transaction per statement
+// The Context variants stop the statement when the context is done
+type Tx interface {
+	All(interface{}) error
+	AllContext(context.Context, interface{}) error
+	Find(interface{}, ...*clause.Clause) error
+	FindContext(context.Context, interface{}, ...*clause.Clause) error
+	FindAll(interface{}, ...*clause.Clause) error
+	FindAllContext(context.Context, interface{}, ...*clause.Clause) error
+	Save(interface{}) error
+	SaveContext(context.Context, interface{}) error
+	DeleteAll(interface{}) error
+	DeleteAllContext(context.Context, interface{}) error
+	DeleteWhere(interface{}, ...*clause.Clause) error
+	DeleteWhereContext(context.Context, interface{}, ...*clause.Clause) error
+}
+
 // DB contains the methods needed to store and read data from the underlying
 // database
 type DB interface {
+	Tx
 	Ping() error
 	Migrate(string) error
-	All(interface{}) error
-	Find(interface{}, ...*clause.Clause) error
-	FindAll(interface{}, ...*clause.Clause) error
-	Save(interface{}) error
+	Transaction(func(Tx) error) error
+	TransactionContext(context.Context, func(Tx) error) error
+	Vacuum() error
+	VacuumContext(context.Context) error
 	Close() error
 }
 
+// Add the given parameter to the DSN, unless it's already set
+func withParam(dsn, name, value string) string {
+	if strings.Contains(dsn, name+"=") {
+		return dsn
+	}
+
+	if strings.Contains(dsn, "?") {
+		return fmt.Sprintf("%s&%s=%s", dsn, name, value)
+	}
+
+	return fmt.Sprintf("%s?%s=%s", dsn, name, value)
+}
+
+// Begin transactions with a write lock, rather than upgrading a read lock on
+// the first write; SQLite fails an upgrade immediately, without waiting for the
+// busy timeout, when another connection is writing, and the ORM's upserts read
+// before writing
+// Only writes begin transactions; the ORM runs reads outside of them, so they
+// don't wait for the lock
+// The connection settings are passed in the DSN, so that every connection in
+// the pool is opened with them
+func dsn(cfg *config.DBConfig) string {
+	dsn := withParam(cfg.DSN, "_txlock", "immediate")
+
+	if cfg.BusyTimeout != 0 {
+		dsn = withParam(dsn, "_busy_timeout", strconv.FormatInt(cfg.BusyTimeout.Milliseconds(), 10))
+	}
+	if cfg.JournalMode != "" {
+		dsn = withParam(dsn, "_journal_mode", strings.ToUpper(cfg.JournalMode))
+	}
+	if cfg.ForeignKeys {
+		dsn = withParam(dsn, "_foreign_keys", "1")
+	}
+
+	return dsn
+}
+
 // New creates a struct which supports the operations in the DB interface
+// The struct holds a pool of connections, so it's meant to be created once and
+// shared, rather than created per request
 func New(cfg *config.DBConfig) (DB, error) {
-	db, err := sql.Open("sqlite3", cfg.DSN)
+	db, err := sql.Open("sqlite3", dsn(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open DB: %w", err)
 	}
 
+	if cfg.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+
 	return &sqlDB{db: db}, nil
 }
 
@@ -74,18 +146,96 @@ func (sdb *sqlDB) All(ptr interface{}) error {
 	return sdb.client().All(ptr)
 }
 
+func (sdb *sqlDB) AllContext(ctx context.Context, ptr interface{}) error {
+	return sdb.client().AllContext(ctx, ptr)
+}
+
 func (sdb *sqlDB) Find(ptr interface{}, clauses ...*clause.Clause) error {
 	return sdb.client().Find(ptr, clauses...)
 }
 
+func (sdb *sqlDB) FindContext(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
+	return sdb.client().FindContext(ctx, ptr, clauses...)
+}
+
 func (sdb *sqlDB) FindAll(ptr interface{}, clauses ...*clause.Clause) error {
 	return sdb.client().FindAll(ptr, clauses...)
 }
 
+func (sdb *sqlDB) FindAllContext(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
+	return sdb.client().FindAllContext(ctx, ptr, clauses...)
+}
+
 func (sdb *sqlDB) Save(ptr interface{}) error {
 	return sdb.client().Save(ptr)
 }
 
+func (sdb *sqlDB) SaveContext(ctx context.Context, ptr interface{}) error {
+	return sdb.client().SaveContext(ctx, ptr)
+}
+
+func (sdb *sqlDB) DeleteAll(ptr interface{}) error {
+	return sdb.client().DeleteAll(ptr)
+}
+
+func (sdb *sqlDB) DeleteAllContext(ctx context.Context, ptr interface{}) error {
+	return sdb.client().DeleteAllContext(ctx, ptr)
+}
+
+func (sdb *sqlDB) DeleteWhere(ptr interface{}, clauses ...*clause.Clause) error {
+	return sdb.client().DeleteWhere(ptr, clauses...)
+}
+
+func (sdb *sqlDB) DeleteWhereContext(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
+	return sdb.client().DeleteWhereContext(ctx, ptr, clauses...)
+}
+
+// Transaction calls the given function with a transaction, which is committed
+// if the function succeeds and rolled back if it returns an error
+// The function's error is returned as is; the function must only use the
+// transaction, since the DB may not have another connection to spare
+func (sdb *sqlDB) Transaction(fn func(Tx) error) error {
+	return sdb.TransactionContext(context.Background(), fn)
+}
+
+// TransactionContext is like Transaction, but the transaction is rolled back
+// if the context is done before it's committed
+func (sdb *sqlDB) TransactionContext(ctx context.Context, fn func(Tx) error) error {
+	tx, err := sdb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	err = fn(client.NewTx(tx))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Vacuum rebuilds the database file, returning the space freed by deleted rows
+// to the filesystem
+func (sdb *sqlDB) Vacuum() error {
+	return sdb.VacuumContext(context.Background())
+}
+
+func (sdb *sqlDB) VacuumContext(ctx context.Context) error {
+	_, err := sdb.db.ExecContext(ctx, "VACUUM")
+	if err != nil {
+		return fmt.Errorf("failed to vacuum DB: %w", err)
+	}
+
+	return nil
+}
+
 func (sdb *sqlDB) Close() error {
 	err := sdb.db.Close()
 	if err != nil {
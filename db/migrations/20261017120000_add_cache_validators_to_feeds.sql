@@ -0,0 +1,11 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+ALTER TABLE "feeds" ADD "e_tag" varchar(255) DEFAULT '';
+ALTER TABLE "feeds" ADD "last_modified" varchar(255) DEFAULT '';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+ALTER TABLE "feeds" RENAME TO "feeds_backup";
+CREATE TABLE "feeds" ("id" integer primary key autoincrement,"url" varchar(255), "fetch_limit" integer DEFAULT 0);
+INSERT INTO "feeds" SELECT "id","url","fetch_limit" from "feeds_backup";
+DROP TABLE "feeds_backup";
@@ -0,0 +1,12 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+ALTER TABLE "feeds" ADD "fetch_period" integer DEFAULT 0;
+ALTER TABLE "feeds" ADD "next_fetch_at" datetime;
+UPDATE "feeds" set next_fetch_at = CURRENT_TIMESTAMP;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+ALTER TABLE "feeds" RENAME TO "feeds_backup";
+CREATE TABLE "feeds" ("id" integer primary key autoincrement,"url" varchar(255), "fetch_limit" integer DEFAULT 0, "e_tag" varchar(255) DEFAULT '', "last_modified" varchar(255) DEFAULT '');
+INSERT INTO "feeds" SELECT "id","url","fetch_limit","e_tag","last_modified" from "feeds_backup";
+DROP TABLE "feeds_backup";
@@ -0,0 +1,7 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS "statuses" ("id" integer primary key autoincrement,"feed_id" integer unique,"last_success_at" datetime,"last_error_at" datetime,"last_error" text,"consecutive_failures" integer DEFAULT 0,"last_http_status" integer DEFAULT 0);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE "statuses";
@@ -0,0 +1,7 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS "subscriptions" ("id" integer primary key autoincrement,"feed_id" integer unique,"hub" text,"topic" text,"secret" varchar(255),"requested_at" datetime,"expires_at" datetime);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE "subscriptions";
@@ -0,0 +1,18 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Existing rows are backfilled by 20261017160100_backfill_item_hashes.go
+ALTER TABLE "items" ADD "guid" varchar(255) DEFAULT '';
+ALTER TABLE "items" ADD "hash" varchar(64) DEFAULT '';
+CREATE INDEX "items_feed_id_guid" ON "items" ("feed_id","guid");
+CREATE INDEX "items_feed_id_link" ON "items" ("feed_id","link");
+CREATE INDEX "items_feed_id_hash" ON "items" ("feed_id","hash");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX "items_feed_id_guid";
+DROP INDEX "items_feed_id_link";
+DROP INDEX "items_feed_id_hash";
+ALTER TABLE "items" RENAME TO "items_backup";
+CREATE TABLE "items" ("id" integer primary key autoincrement,"name" varchar(255),"email" varchar(255),"title" varchar(255),"description" varchar(255),"link" varchar(255),"published" datetime,"hide" bool,"feed_id" integer,"created_at" datetime);
+INSERT INTO "items" SELECT "id","name","email","title","description","link","published","hide","feed_id","created_at" from "items_backup";
+DROP TABLE "items_backup";
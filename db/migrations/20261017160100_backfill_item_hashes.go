@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddMigration(upBackfillItemHashes, downBackfillItemHashes)
+}
+
+// Copy of feed.CanonicalLink: lowercase the scheme & host, remove default
+// ports, and strip tracking parameters from the query
+func backfillCanonicalLink(link string) string {
+	link = strings.TrimSpace(link)
+
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+
+	query := u.Query()
+	stripped := false
+	for param := range query {
+		switch lower := strings.ToLower(param); {
+		case strings.HasPrefix(lower, "utm_"),
+			lower == "fbclid", lower == "gclid", lower == "igshid", lower == "mc_cid", lower == "mc_eid":
+			query.Del(param)
+			stripped = true
+		}
+	}
+
+	if stripped {
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+// Copy of feed.ContentHash: hash the unescaped title & description, ignoring
+// whitespace & case
+func backfillContentHash(title, description string) string {
+	title = strings.ToLower(strings.Join(strings.Fields(title), " "))
+	description = strings.ToLower(strings.Join(strings.Fields(description), " "))
+	if title == "" && description == "" {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write([]byte(title))
+	h.Write([]byte{0})
+	h.Write([]byte(description))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type itemContent struct {
+	id          uint
+	title       string
+	description string
+	link        string
+}
+
+// Canonicalize the links of existing items and compute their content hashes,
+// so that they're recognized by the new deduplication rules
+// GUIDs weren't stored, so they can't be backfilled
+func upBackfillItemHashes(tx *sql.Tx) error {
+	var items []*itemContent
+	err := readRows(tx, `SELECT "id","title","description","link" FROM "items"`, func(rows *sql.Rows) error {
+		var i itemContent
+		items = append(items, &i)
+		return rows.Scan(&i.id, &i.title, &i.description, &i.link)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read items: %w", err)
+	}
+
+	for _, i := range items {
+		// Stored values are escaped; see feed.Item.FromGofeedItem
+		link := html.EscapeString(backfillCanonicalLink(html.UnescapeString(i.link)))
+		hash := backfillContentHash(html.UnescapeString(i.title), html.UnescapeString(i.description))
+
+		_, err = tx.Exec(`UPDATE "items" SET "link" = ?, "hash" = ? WHERE "id" = ?`, link, hash, i.id)
+		if err != nil {
+			return fmt.Errorf("failed to update item: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Canonicalized links can't be restored, and the hashes are dropped along
+// with their column
+func downBackfillItemHashes(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS "item_revisions" ("id" integer primary key autoincrement,"item_id" integer,"title" varchar(255),"description" varchar(255),"hash" varchar(64),"created_at" datetime);
+CREATE INDEX "item_revisions_item_id" ON "item_revisions" ("item_id");
+ALTER TABLE "items" ADD "revised_at" datetime;
+UPDATE "items" set revised_at = '0001-01-01 00:00:00+00:00';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE "item_revisions";
+ALTER TABLE "items" RENAME TO "items_backup";
+CREATE TABLE "items" ("id" integer primary key autoincrement,"name" varchar(255),"email" varchar(255),"title" varchar(255),"description" varchar(255),"link" varchar(255),"published" datetime,"hide" bool,"feed_id" integer,"created_at" datetime,"guid" varchar(255) DEFAULT '',"hash" varchar(64) DEFAULT '');
+INSERT INTO "items" SELECT "id","name","email","title","description","link","published","hide","feed_id","created_at","guid","hash" from "items_backup";
+DROP TABLE "items_backup";
+CREATE INDEX "items_feed_id_guid" ON "items" ("feed_id","guid");
+CREATE INDEX "items_feed_id_link" ON "items" ("feed_id","link");
+CREATE INDEX "items_feed_id_hash" ON "items" ("feed_id","hash");
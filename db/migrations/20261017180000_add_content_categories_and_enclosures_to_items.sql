@@ -0,0 +1,22 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+ALTER TABLE "items" ADD "content" text DEFAULT '';
+ALTER TABLE "items" ADD "image" varchar(255) DEFAULT '';
+ALTER TABLE "items" ADD "updated" datetime;
+UPDATE "items" set updated = '0001-01-01 00:00:00+00:00';
+CREATE TABLE IF NOT EXISTS "item_categories" ("id" integer primary key autoincrement,"item_id" integer,"name" varchar(255));
+CREATE INDEX "item_categories_item_id" ON "item_categories" ("item_id");
+CREATE TABLE IF NOT EXISTS "enclosures" ("id" integer primary key autoincrement,"item_id" integer,"url" text,"length" integer DEFAULT 0,"type" varchar(255));
+CREATE INDEX "enclosures_item_id" ON "enclosures" ("item_id");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE "item_categories";
+DROP TABLE "enclosures";
+ALTER TABLE "items" RENAME TO "items_backup";
+CREATE TABLE "items" ("id" integer primary key autoincrement,"name" varchar(255),"email" varchar(255),"title" varchar(255),"description" varchar(255),"link" varchar(255),"published" datetime,"hide" bool,"feed_id" integer,"created_at" datetime,"guid" varchar(255) DEFAULT '',"hash" varchar(64) DEFAULT '',"revised_at" datetime);
+INSERT INTO "items" SELECT "id","name","email","title","description","link","published","hide","feed_id","created_at","guid","hash","revised_at" from "items_backup";
+DROP TABLE "items_backup";
+CREATE INDEX "items_feed_id_guid" ON "items" ("feed_id","guid");
+CREATE INDEX "items_feed_id_link" ON "items" ("feed_id","link");
+CREATE INDEX "items_feed_id_hash" ON "items" ("feed_id","hash");
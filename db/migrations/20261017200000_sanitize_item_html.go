@@ -0,0 +1,260 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/pressly/goose"
+	nethtml "golang.org/x/net/html"
+)
+
+func init() {
+	goose.AddMigration(upSanitizeItemHTML, downSanitizeItemHTML)
+}
+
+// Return the attributes allowed on the tag, or false if the tag is removed
+// while its text is kept
+func sanitizeAllowedAttrs(tag string) ([]string, bool) {
+	switch tag {
+	case "a":
+		return []string{"href", "title"}, true
+	case "img":
+		return []string{"src", "alt", "title", "width", "height"}, true
+	case "b", "blockquote", "br", "code", "em", "h1", "h2", "h3", "h4", "h5", "h6",
+		"i", "li", "ol", "p", "pre", "strong", "ul":
+		return nil, true
+	}
+
+	return nil, false
+}
+
+// Return whether the tag is removed along with its content
+func sanitizeDropsTag(tag string) bool {
+	switch tag {
+	case "embed", "head", "iframe", "math", "noscript", "object", "script",
+		"select", "style", "svg", "template", "textarea", "title":
+		return true
+	}
+
+	return false
+}
+
+// Resolve the URL of the tag's attribute against the base URL, returning "" if
+// it can't be parsed or its scheme isn't allowed
+func sanitizeURL(tag, rawURL string, base *url.URL) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.String()
+	case "mailto":
+		if tag == "a" {
+			return u.String()
+		}
+	}
+
+	return ""
+}
+
+// Return the start tag with only its allowed attributes, or false if it's an
+// image without a valid source
+func sanitizeTag(token nethtml.Token, allowed []string, base *url.URL) (string, bool) {
+	var b strings.Builder
+	b.WriteString("<" + token.Data)
+
+	var hasSrc bool
+	for _, attr := range token.Attr {
+		var ok bool
+		for _, key := range allowed {
+			ok = ok || attr.Key == key
+		}
+		if attr.Namespace != "" || !ok {
+			continue
+		}
+
+		val := attr.Val
+		if attr.Key == "href" || attr.Key == "src" {
+			val = sanitizeURL(token.Data, val, base)
+			if val == "" {
+				continue
+			}
+		}
+		hasSrc = hasSrc || attr.Key == "src"
+
+		b.WriteString(" " + attr.Key + `="` + nethtml.EscapeString(val) + `"`)
+	}
+
+	if token.Data == "img" && !hasSrc {
+		return "", false
+	}
+
+	return b.String() + ">", true
+}
+
+// Copy of feed.Sanitize: keep only the allowed tags & attributes, resolving
+// URLs against the base URL, closing unclosed tags, and escaping text
+func sanitize(fragment string, base *url.URL) string {
+	var b strings.Builder
+	var open []string
+	var dropped []string
+
+	z := nethtml.NewTokenizer(strings.NewReader(fragment))
+	for tt := z.Next(); tt != nethtml.ErrorToken; tt = z.Next() {
+		token := z.Token()
+		tag := token.Data
+
+		if len(dropped) > 0 {
+			switch {
+			case tt == nethtml.StartTagToken && sanitizeDropsTag(tag):
+				dropped = append(dropped, tag)
+			case tt == nethtml.EndTagToken && tag == dropped[len(dropped)-1]:
+				dropped = dropped[:len(dropped)-1]
+			}
+			continue
+		}
+
+		switch tt {
+		case nethtml.TextToken:
+			b.WriteString(nethtml.EscapeString(token.Data))
+
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			if sanitizeDropsTag(tag) {
+				if tt == nethtml.StartTagToken {
+					dropped = append(dropped, tag)
+				}
+				continue
+			}
+
+			allowed, ok := sanitizeAllowedAttrs(tag)
+			if !ok {
+				continue
+			}
+
+			start, ok := sanitizeTag(token, allowed, base)
+			if !ok {
+				continue
+			}
+			b.WriteString(start)
+
+			if tag == "br" || tag == "img" {
+				continue
+			}
+			if tt == nethtml.SelfClosingTagToken {
+				b.WriteString("</" + tag + ">")
+				continue
+			}
+			open = append(open, tag)
+
+		case nethtml.EndTagToken:
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != tag {
+					continue
+				}
+
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+
+	return b.String()
+}
+
+type itemHTML struct {
+	table       string
+	id          uint
+	description string
+	content     sql.NullString
+	feedURL     sql.NullString
+}
+
+// Select the HTML fields of items & item revisions, along with the URL of
+// their feed; revisions have no content
+func selectItemHTML(tx *sql.Tx) ([]*itemHTML, error) {
+	queries := map[string]string{
+		"items": `SELECT "items"."id","items"."description","items"."content","feeds"."url" ` +
+			`FROM "items" LEFT JOIN "feeds" ON "feeds"."id" = "items"."feed_id"`,
+		"item_revisions": `SELECT "item_revisions"."id","item_revisions"."description",NULL,"feeds"."url" ` +
+			`FROM "item_revisions" LEFT JOIN "items" ON "items"."id" = "item_revisions"."item_id" ` +
+			`LEFT JOIN "feeds" ON "feeds"."id" = "items"."feed_id"`,
+	}
+
+	var rowsHTML []*itemHTML
+	for _, table := range []string{"items", "item_revisions"} {
+		err := readRows(tx, queries[table], func(rows *sql.Rows) error {
+			i := itemHTML{table: table}
+			rowsHTML = append(rowsHTML, &i)
+			return rows.Scan(&i.id, &i.description, &i.content, &i.feedURL)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+	}
+
+	return rowsHTML, nil
+}
+
+func updateItemHTML(tx *sql.Tx, convert func(string, *url.URL) string) error {
+	rowsHTML, err := selectItemHTML(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range rowsHTML {
+		// Relative links are resolved against the feed's URL, since the
+		// website's URL wasn't stored
+		var base *url.URL
+		if i.feedURL.Valid {
+			base, err = url.Parse(i.feedURL.String)
+			if err != nil {
+				base = nil
+			}
+		}
+
+		description := convert(i.description, base)
+		if i.table == "item_revisions" {
+			_, err = tx.Exec(`UPDATE "item_revisions" SET "description" = ? WHERE "id" = ?`, description, i.id)
+		} else {
+			content := convert(i.content.String, base)
+			_, err = tx.Exec(`UPDATE "items" SET "description" = ?, "content" = ? WHERE "id" = ?`, description, content, i.id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", i.table, err)
+		}
+	}
+
+	return nil
+}
+
+// Replace the escaped HTML of existing items with sanitized HTML, so that it's
+// displayed as markup rather than text
+func upSanitizeItemHTML(tx *sql.Tx) error {
+	return updateItemHTML(tx, func(escaped string, base *url.URL) string {
+		return sanitize(html.UnescapeString(escaped), base)
+	})
+}
+
+// Escape the sanitized HTML again; anything removed by sanitizing can't be
+// restored
+func downSanitizeItemHTML(tx *sql.Tx) error {
+	return updateItemHTML(tx, func(sanitized string, _ *url.URL) string {
+		return html.EscapeString(sanitized)
+	})
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS "alerts" ("id" integer primary key autoincrement,"item_id" integer,"feed_id" integer,"watchlist" varchar(255),"term" varchar(255),"notified" bool,"created_at" datetime);
+CREATE INDEX "alerts_item_id" ON "alerts" ("item_id");
+CREATE INDEX "alerts_notified" ON "alerts" ("notified");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE "alerts";
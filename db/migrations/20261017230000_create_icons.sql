@@ -0,0 +1,8 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS "icons" ("id" integer primary key autoincrement,"feed_id" integer,"url" varchar(255),"content_type" varchar(255),"fetched_at" datetime);
+CREATE UNIQUE INDEX "icons_feed_id" ON "icons" ("feed_id");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE "icons";
@@ -0,0 +1,16 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS "tombstones" ("id" integer primary key autoincrement,"feed_id" integer,"guid" varchar(255),"link" varchar(255),"hash" varchar(64),"created_at" datetime);
+CREATE INDEX "tombstones_feed_id_guid" ON "tombstones" ("feed_id","guid");
+CREATE INDEX "tombstones_feed_id_link" ON "tombstones" ("feed_id","link");
+CREATE INDEX "tombstones_feed_id_hash" ON "tombstones" ("feed_id","hash");
+ALTER TABLE "feeds" ADD "purged_at" datetime;
+UPDATE "feeds" set purged_at = '0001-01-01 00:00:00+00:00';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE "tombstones";
+ALTER TABLE "feeds" RENAME TO "feeds_backup";
+CREATE TABLE "feeds" ("id" integer primary key autoincrement,"url" varchar(255), "fetch_limit" integer DEFAULT 0, "e_tag" varchar(255) DEFAULT '', "last_modified" varchar(255) DEFAULT '', "fetch_period" integer DEFAULT 0, "next_fetch_at" datetime, "full_text" bool DEFAULT 0, "name" varchar(255) DEFAULT '', "title" varchar(255) DEFAULT '', "link" varchar(255) DEFAULT '', "description" varchar(255) DEFAULT '', "image" varchar(255) DEFAULT '', "language" varchar(255) DEFAULT '');
+INSERT INTO "feeds" SELECT "id","url","fetch_limit","e_tag","last_modified","fetch_period","next_fetch_at","full_text","name","title","link","description","image","language" from "feeds_backup";
+DROP TABLE "feeds_backup";
@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Links & images stored before their schemes were checked, ex. javascript: links
+UPDATE "items" SET "link" = '' WHERE lower(trim("link")) NOT LIKE 'http://%' AND lower(trim("link")) NOT LIKE 'https://%' AND lower(trim("link")) NOT LIKE 'mailto:%';
+UPDATE "items" SET "image" = '' WHERE lower(trim("image")) NOT LIKE 'http://%' AND lower(trim("image")) NOT LIKE 'https://%';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+-- The cleared links can't be restored
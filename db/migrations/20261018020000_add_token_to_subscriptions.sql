@@ -0,0 +1,15 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Existing subscriptions get a token, and are requested again with their new
+-- callback URLs
+ALTER TABLE "subscriptions" ADD "token" varchar(64);
+UPDATE "subscriptions" SET token = lower(hex(randomblob(32))), requested_at = '0001-01-01 00:00:00+00:00';
+CREATE UNIQUE INDEX "subscriptions_token" ON "subscriptions" ("token");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX "subscriptions_token";
+ALTER TABLE "subscriptions" RENAME TO "subscriptions_backup";
+CREATE TABLE "subscriptions" ("id" integer primary key autoincrement,"feed_id" integer unique,"hub" text,"topic" text,"secret" varchar(255),"requested_at" datetime,"expires_at" datetime);
+INSERT INTO "subscriptions" SELECT "id","feed_id","hub","topic","secret","requested_at","expires_at" from "subscriptions_backup";
+DROP TABLE "subscriptions_backup";
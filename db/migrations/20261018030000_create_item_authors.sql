@@ -0,0 +1,8 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+CREATE TABLE IF NOT EXISTS "item_authors" ("id" integer primary key autoincrement,"item_id" integer,"name" varchar(255));
+CREATE INDEX "item_authors_item_id" ON "item_authors" ("item_id");
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE "item_authors";
@@ -0,0 +1,12 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+ALTER TABLE "item_revisions" ADD "link" varchar(255) DEFAULT '';
+ALTER TABLE "item_revisions" ADD "content" text DEFAULT '';
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+ALTER TABLE "item_revisions" RENAME TO "item_revisions_backup";
+CREATE TABLE "item_revisions" ("id" integer primary key autoincrement,"item_id" integer,"title" varchar(255),"description" varchar(255),"hash" varchar(64),"created_at" datetime);
+INSERT INTO "item_revisions" SELECT "id","item_id","title","description","hash","created_at" from "item_revisions_backup";
+DROP TABLE "item_revisions_backup";
+CREATE INDEX "item_revisions_item_id" ON "item_revisions" ("item_id");
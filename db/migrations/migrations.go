@@ -0,0 +1,43 @@
+// Package migrations contains the DB migrations which can't be expressed in
+// SQL; they're registered with goose when the package is imported, and run
+// alongside the SQL migrations in this directory
+// A migration must do the same thing whenever it runs, however old the DB it's
+// applied to, so migrations don't call the other packages of the app; the few
+// functions which decide what they store are copied into them as they were
+// when the migration was written, and aren't changed afterwards
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Run the query in the transaction, calling scan with each row
+// All rows are read before returning, rather than updated as they're read,
+// since the transaction's connection is busy until the rows are closed
+func readRows(tx *sql.Tx, query string, scan func(*sql.Rows) error) error {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to query rows: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = scan(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	err = rows.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close rows: %w", err)
+	}
+
+	return nil
+}
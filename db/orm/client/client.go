@@ -1,18 +1,28 @@
 package client
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"gonews/db/orm/query"
 	"gonews/db/orm/query/clause"
 )
 
+// Client contains the methods for reading & writing models
+// The Context variants stop the query when the context is done
 type Client interface {
 	All(interface{}) error
+	AllContext(context.Context, interface{}) error
 	DeleteAll(interface{}) error
+	DeleteAllContext(context.Context, interface{}) error
+	DeleteWhere(interface{}, ...*clause.Clause) error
+	DeleteWhereContext(context.Context, interface{}, ...*clause.Clause) error
 	Find(interface{}, ...*clause.Clause) error
+	FindContext(context.Context, interface{}, ...*clause.Clause) error
 	FindAll(interface{}, ...*clause.Clause) error
+	FindAllContext(context.Context, interface{}, ...*clause.Clause) error
 	Save(interface{}) error
+	SaveContext(context.Context, interface{}) error
 }
 
 func New(db *sql.DB) Client {
@@ -21,25 +31,74 @@ func New(db *sql.DB) Client {
 	}
 }
 
+// NewTx creates a Client which executes its queries in the given transaction,
+// which the caller commits or rolls back
+func NewTx(tx *sql.Tx) Client {
+	return &client{
+		tx: tx,
+	}
+}
+
 type client struct {
 	db *sql.DB
+	tx *sql.Tx
+}
+
+// Execute the query in the client's transaction, if any, and otherwise in a
+// transaction of its own
+func (c *client) exec(ctx context.Context, q query.Query) error {
+	if c.tx != nil {
+		return q.ExecTxContext(ctx, c.tx)
+	}
+
+	return q.ExecContext(ctx, c.db)
 }
 
 // All fetches the models from the appropriate table and assigns the result to the given interface
 func (c *client) All(results interface{}) error {
+	return c.AllContext(context.Background(), results)
+}
+
+// AllContext is like All, but stops the query when the context is done
+func (c *client) AllContext(ctx context.Context, results interface{}) error {
 	// All just calls FindAll without using any clauses
 	// It exists b/c "All(...)" is faster to type than "FindAll(...)"
-	return c.FindAll(results)
+	return c.FindAllContext(ctx, results)
 }
 
 // DeleteAll deletes the given models from the appropriate table
 func (c *client) DeleteAll(models interface{}) error {
+	return c.DeleteAllContext(context.Background(), models)
+}
+
+// DeleteAllContext is like DeleteAll, but stops the query when the context is done
+func (c *client) DeleteAllContext(ctx context.Context, models interface{}) error {
 	query, err := query.Delete(models)
 	if err != nil {
 		return fmt.Errorf("failed to create query: %w", err)
 	}
 
-	err = query.Exec(c.db)
+	err = c.exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWhere deletes the models matching the given query clauses from the table of the given model
+func (c *client) DeleteWhere(model interface{}, clauses ...*clause.Clause) error {
+	return c.DeleteWhereContext(context.Background(), model, clauses...)
+}
+
+// DeleteWhereContext is like DeleteWhere, but stops the query when the context is done
+func (c *client) DeleteWhereContext(ctx context.Context, model interface{}, clauses ...*clause.Clause) error {
+	query, err := query.DeleteWhere(model, clauses...)
+	if err != nil {
+		return fmt.Errorf("failed to create query: %w", err)
+	}
+
+	err = c.exec(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -49,12 +108,17 @@ func (c *client) DeleteAll(models interface{}) error {
 
 // Find fetches the first model from the appropriate table and assigns the result to the given interface, subject to the given query clauses
 func (c *client) Find(result interface{}, clauses ...*clause.Clause) error {
+	return c.FindContext(context.Background(), result, clauses...)
+}
+
+// FindContext is like Find, but stops the query when the context is done
+func (c *client) FindContext(ctx context.Context, result interface{}, clauses ...*clause.Clause) error {
 	query, err := query.SelectOne(result, clauses...)
 	if err != nil {
 		return fmt.Errorf("failed to create query: %w", err)
 	}
 
-	err = query.Exec(c.db)
+	err = c.exec(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -64,12 +128,17 @@ func (c *client) Find(result interface{}, clauses ...*clause.Clause) error {
 
 // FindAll fetches the models from the appropriate table and assigns the result to the given interface, subject to the given query clauses
 func (c *client) FindAll(results interface{}, clauses ...*clause.Clause) error {
+	return c.FindAllContext(context.Background(), results, clauses...)
+}
+
+// FindAllContext is like FindAll, but stops the query when the context is done
+func (c *client) FindAllContext(ctx context.Context, results interface{}, clauses ...*clause.Clause) error {
 	query, err := query.Select(results, clauses...)
 	if err != nil {
 		return fmt.Errorf("failed to create query: %w", err)
 	}
 
-	err = query.Exec(c.db)
+	err = c.exec(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -79,12 +148,17 @@ func (c *client) FindAll(results interface{}, clauses ...*clause.Clause) error {
 
 // Save inserts the model into the appropriate table if it has an unspecified ID, and updates it otherwise
 func (c *client) Save(model interface{}) error {
+	return c.SaveContext(context.Background(), model)
+}
+
+// SaveContext is like Save, but stops the query when the context is done
+func (c *client) SaveContext(ctx context.Context, model interface{}) error {
 	query, err := query.Upsert(model)
 	if err != nil {
 		return fmt.Errorf("failed to create query: %w", err)
 	}
 
-	err = query.Exec(c.db)
+	err = c.exec(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
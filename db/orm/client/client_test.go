@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"gonews/db/orm/query"
 	"gonews/db/orm/query/clause"
@@ -127,6 +128,66 @@ func TestDeleteAllReturnsErrorIfIdIsMissing(t *testing.T) {
 	assert.True(t, errors.Is(err, query.ErrMissingIdField))
 }
 
+func TestDeleteWhere(t *testing.T) {
+	db := test.InitDB(t)
+	client := New(db)
+
+	model1 := test.Model{
+		Bool:   true,
+		String: "abc",
+	}
+	model2 := test.Model{
+		Bool:   false,
+		String: "def",
+	}
+	model3 := test.Model{
+		Bool:   true,
+		String: "ghi",
+	}
+
+	err := client.Save(&model1)
+	assert.NoError(t, err)
+
+	err = client.Save(&model2)
+	assert.NoError(t, err)
+
+	err = client.Save(&model3)
+	assert.NoError(t, err)
+
+	err = client.DeleteWhere(&test.Model{}, clause.Where("bool = ?", true))
+	assert.NoError(t, err)
+
+	var models []*test.Model
+	err = client.All(&models)
+	assert.Len(t, models, 1)
+	test.AssertModelsEqual(t, &model2, models[0])
+
+	// Deleting nothing isn't an error
+	err = client.DeleteWhere(&test.Model{}, clause.Where("bool = ?", true))
+	assert.NoError(t, err)
+}
+
+func TestDeleteWhereReturnsErrorIfArgumentInvalid(t *testing.T) {
+	db := test.InitDB(t)
+	client := New(db)
+
+	var models []*test.Model
+	err := client.DeleteWhere(&models)
+	assert.True(t, errors.Is(err, query.ErrInvalidModelArg))
+
+	var i int
+	err = client.DeleteWhere(&i)
+	assert.True(t, errors.Is(err, query.ErrInvalidModelArg))
+}
+
+func TestDeleteWhereReturnsErrorIfIdIsMissing(t *testing.T) {
+	db := test.InitDB(t)
+	client := New(db)
+
+	err := client.DeleteWhere(&test.IdMissingModel{})
+	assert.True(t, errors.Is(err, query.ErrMissingIdField))
+}
+
 func TestFind(t *testing.T) {
 	db := test.InitDB(t)
 	client := New(db)
@@ -185,6 +246,25 @@ func TestFindReturnsErrorIfModelNotFound(t *testing.T) {
 	assert.True(t, errors.Is(err, query.ErrModelNotFound))
 }
 
+func TestFindContextReturnsErrorIfContextCanceled(t *testing.T) {
+	db := test.InitDB(t)
+	client := New(db)
+
+	model := test.Model{String: "abc"}
+	err := client.Save(&model)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var matchingModel test.Model
+	err = client.FindContext(
+		ctx,
+		&matchingModel,
+		clause.Where("string = ?", "abc"))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
 func TestFindAll(t *testing.T) {
 	db := test.InitDB(t)
 	client := New(db)
@@ -307,6 +387,23 @@ func TestSaveSetsID(t *testing.T) {
 	assert.NotZero(t, model.ID)
 }
 
+func TestSaveContextReturnsErrorIfContextCanceled(t *testing.T) {
+	db := test.InitDB(t)
+	client := New(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	model := test.Model{String: "abc"}
+	err := client.SaveContext(ctx, &model)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	var matchingModels []*test.Model
+	err = client.All(&matchingModels)
+	assert.NoError(t, err)
+	assert.Empty(t, matchingModels)
+}
+
 func TestSaveSetsCreatedAtIfPresent(t *testing.T) {
 	db := test.InitDB(t)
 
@@ -348,6 +445,41 @@ func TestSaveUpdatesUpdatedAtIfPresent(t *testing.T) {
 	assert.True(t, model.UpdatedAt.After(previousUpdatedAt))
 }
 
+func TestSavePluralizesTableNames(t *testing.T) {
+	db := test.InitDB(t)
+	client := New(db)
+
+	status := test.Status{
+		String: "abc",
+	}
+	err := client.Save(&status)
+	assert.NoError(t, err)
+
+	category := test.Category{
+		String: "def",
+	}
+	err = client.Save(&category)
+	assert.NoError(t, err)
+
+	var statuses []*test.Status
+	err = client.All(&statuses)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, status.String, statuses[0].String)
+
+	var matchingCategory test.Category
+	err = client.Find(&matchingCategory, clause.Where("id = ?", category.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, category.String, matchingCategory.String)
+
+	statuses[0].String = "ghi"
+	err = client.Save(statuses[0])
+	assert.NoError(t, err)
+
+	err = client.DeleteAll(&[]*test.Category{&category})
+	assert.NoError(t, err)
+}
+
 //// Clause tests
 
 func TestGroupByClause(t *testing.T) {
@@ -613,3 +745,64 @@ func TestUnionClause(t *testing.T) {
 	test.AssertModelsEqual(t, &model1, matchingModels[2])
 	test.AssertModelsEqual(t, &model2, matchingModels[3])
 }
+
+func TestSaveIgnoresSkippedFields(t *testing.T) {
+	db := test.InitDB(t)
+	client := New(db)
+
+	model := test.SkippedFieldsModel{
+		String: "abc",
+		Models: []*test.Model{{String: "def"}},
+		Bool:   true,
+	}
+	err := client.Save(&model)
+	assert.NoError(t, err)
+
+	model.String = "ghi"
+	err = client.Save(&model)
+	assert.NoError(t, err)
+
+	var models []*test.SkippedFieldsModel
+	err = client.All(&models)
+	assert.NoError(t, err)
+	assert.Len(t, models, 1)
+	assert.Equal(t, "ghi", models[0].String)
+	assert.True(t, models[0].Bool)
+	assert.Nil(t, models[0].Models)
+
+	var matchingModel test.SkippedFieldsModel
+	err = client.Find(&matchingModel, clause.Where("id = ?", model.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, "ghi", matchingModel.String)
+	assert.Nil(t, matchingModel.Models)
+}
+
+func TestNewTx(t *testing.T) {
+	db := test.InitDB(t)
+	client := New(db)
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+
+	model := test.Model{String: "abc"}
+	err = NewTx(tx).Save(&model)
+	assert.NoError(t, err)
+
+	var matchingModel test.Model
+	err = NewTx(tx).Find(&matchingModel, clause.Where("id = ?", model.ID))
+	assert.NoError(t, err)
+	test.AssertModelsEqual(t, &model, &matchingModel)
+
+	err = NewTx(tx).DeleteAll(&[]*test.Model{&model})
+	assert.NoError(t, err)
+
+	err = NewTx(tx).Find(&matchingModel, clause.Where("id = ?", model.ID))
+	assert.True(t, errors.Is(err, query.ErrModelNotFound))
+
+	assert.NoError(t, tx.Rollback())
+
+	var matchingModels []*test.Model
+	err = client.All(&matchingModels)
+	assert.NoError(t, err)
+	assert.Empty(t, matchingModels)
+}
@@ -1,6 +1,7 @@
 package query
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"gonews/db/orm/query/clause"
@@ -67,6 +68,12 @@ func modelsTypeHasID(models interface{}) bool {
 	return found
 }
 
+// Fields tagged with `orm:"-"` aren't stored in the model's table, ex. slices
+// of related models
+func isSkipped(field reflect.StructField) bool {
+	return field.Tag.Get("orm") == "-"
+}
+
 func isUpper(b byte) bool {
 	return b >= 'A' && b <= 'Z'
 }
@@ -106,22 +113,48 @@ func toSnake(s string) string {
 	return b.String()
 }
 
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiou", b) != -1
+}
+
+// Converts a model name to its table name; ex. "FeedItem" -> "feed_items",
+// "Status" -> "statuses", "Category" -> "categories"
+func toTable(modelName string) string {
+	snake := toSnake(modelName)
+
+	n := len(snake)
+	switch {
+	case strings.HasSuffix(snake, "s"),
+		strings.HasSuffix(snake, "x"),
+		strings.HasSuffix(snake, "ch"),
+		strings.HasSuffix(snake, "sh"):
+		return fmt.Sprintf("%ses", snake)
+	case n > 1 && snake[n-1] == 'y' && !isVowel(snake[n-2]):
+		return fmt.Sprintf("%sies", snake[:n-1])
+	}
+
+	return fmt.Sprintf("%ss", snake)
+}
+
 func modelTable(model interface{}) string {
 	modelType := modelType(model)
 	modelName := modelType.Name()
-	return fmt.Sprintf("%ss", toSnake(modelName))
+	return toTable(modelName)
 }
 
 func modelsTable(models interface{}) string {
 	modelType := modelsType(models)
 	modelName := modelType.Name()
-	return fmt.Sprintf("%ss", toSnake(modelName))
+	return toTable(modelName)
 }
 
 // Query contains the methods needed to execute a SQL query in a given database/transaction
+// The Context variants stop the query when the context is done
 type Query interface {
 	Exec(*sql.DB) error
+	ExecContext(context.Context, *sql.DB) error
 	ExecTx(*sql.Tx) error
+	ExecTxContext(context.Context, *sql.Tx) error
 }
 
 type query struct {
@@ -140,14 +173,22 @@ func (q *query) addAll(clauses ...*clause.Clause) {
 	}
 }
 
-func exec(q Query, db *sql.DB) error {
-	tx, err := db.Begin()
+// Prepares statements, either in a transaction or directly on the DB
+type preparer interface {
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+}
+
+// Execute the query in a transaction of its own
+// Transactions begin with a write lock, so only queries which write use one;
+// selects run directly on the DB, which SQLite runs in a read transaction
+func exec(ctx context.Context, q Query, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create DB transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	err = q.ExecTx(tx)
+	err = q.ExecTxContext(ctx, tx)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -166,10 +207,18 @@ type deleteQuery struct {
 }
 
 func (q *deleteQuery) Exec(db *sql.DB) error {
-	return exec(q, db)
+	return q.ExecContext(context.Background(), db)
+}
+
+func (q *deleteQuery) ExecContext(ctx context.Context, db *sql.DB) error {
+	return exec(ctx, q, db)
 }
 
 func (q *deleteQuery) ExecTx(tx *sql.Tx) error {
+	return q.ExecTxContext(context.Background(), tx)
+}
+
+func (q *deleteQuery) ExecTxContext(ctx context.Context, tx *sql.Tx) error {
 	modelsVal := reflect.Indirect(reflect.ValueOf(q.models))
 
 	var ids []interface{}
@@ -183,13 +232,13 @@ func (q *deleteQuery) ExecTx(tx *sql.Tx) error {
 
 	q.addAll(clause.Where("id"), clause.In(ids...))
 
-	stmt, err := tx.Prepare(q.str)
-	defer stmt.Close()
+	stmt, err := tx.PrepareContext(ctx, q.str)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(ids...)
+	res, err := stmt.ExecContext(ctx, ids...)
 	if err != nil {
 		return fmt.Errorf("failed to execute prepared statement: %w", err)
 	}
@@ -205,27 +254,70 @@ func (q *deleteQuery) ExecTx(tx *sql.Tx) error {
 	return nil
 }
 
+type deleteWhereQuery struct {
+	query
+}
+
+func (q *deleteWhereQuery) Exec(db *sql.DB) error {
+	return q.ExecContext(context.Background(), db)
+}
+
+func (q *deleteWhereQuery) ExecContext(ctx context.Context, db *sql.DB) error {
+	return exec(ctx, q, db)
+}
+
+func (q *deleteWhereQuery) ExecTx(tx *sql.Tx) error {
+	return q.ExecTxContext(context.Background(), tx)
+}
+
+func (q *deleteWhereQuery) ExecTxContext(ctx context.Context, tx *sql.Tx) error {
+	stmt, err := tx.PrepareContext(ctx, q.str)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, q.args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute prepared statement: %w", err)
+	}
+
+	return nil
+}
+
 type selectCountQuery struct {
 	query
 	result *int
 }
 
 func (q *selectCountQuery) Exec(db *sql.DB) error {
-	return exec(q, db)
+	return q.ExecContext(context.Background(), db)
+}
+
+func (q *selectCountQuery) ExecContext(ctx context.Context, db *sql.DB) error {
+	return q.run(ctx, db)
 }
 
 func (q *selectCountQuery) ExecTx(tx *sql.Tx) error {
-	stmt, err := tx.Prepare(q.str)
-	defer stmt.Close()
+	return q.ExecTxContext(context.Background(), tx)
+}
+
+func (q *selectCountQuery) ExecTxContext(ctx context.Context, tx *sql.Tx) error {
+	return q.run(ctx, tx)
+}
+
+func (q *selectCountQuery) run(ctx context.Context, p preparer) error {
+	stmt, err := p.PrepareContext(ctx, q.str)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement")
 	}
+	defer stmt.Close()
 
-	rows, err := stmt.Query(q.args...)
-	defer rows.Close()
+	rows, err := stmt.QueryContext(ctx, q.args...)
 	if err != nil {
 		return fmt.Errorf("failed to execute query")
 	}
+	defer rows.Close()
 
 	if !rows.Next() {
 		return fmt.Errorf("not result returned")
@@ -250,21 +342,33 @@ type selectQuery struct {
 }
 
 func (q *selectQuery) Exec(db *sql.DB) error {
-	return exec(q, db)
+	return q.ExecContext(context.Background(), db)
+}
+
+func (q *selectQuery) ExecContext(ctx context.Context, db *sql.DB) error {
+	return q.run(ctx, db)
 }
 
 func (q *selectQuery) ExecTx(tx *sql.Tx) error {
-	stmt, err := tx.Prepare(q.str)
-	defer stmt.Close()
+	return q.ExecTxContext(context.Background(), tx)
+}
+
+func (q *selectQuery) ExecTxContext(ctx context.Context, tx *sql.Tx) error {
+	return q.run(ctx, tx)
+}
+
+func (q *selectQuery) run(ctx context.Context, p preparer) error {
+	stmt, err := p.PrepareContext(ctx, q.str)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
+	defer stmt.Close()
 
-	rows, err := stmt.Query(q.args...)
-	defer rows.Close()
+	rows, err := stmt.QueryContext(ctx, q.args...)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
+	defer rows.Close()
 
 	val := reflect.Indirect(reflect.ValueOf(q.results))
 	slicValue := reflect.Indirect(reflect.New(reflect.SliceOf(val.Type().Elem())))
@@ -272,6 +376,10 @@ func (q *selectQuery) ExecTx(tx *sql.Tx) error {
 		modelValue := reflect.Indirect(reflect.New(val.Type().Elem().Elem()))
 		fieldPointers := []interface{}{}
 		for idx := 0; idx < modelValue.NumField(); idx++ {
+			if isSkipped(modelValue.Type().Field(idx)) {
+				continue
+			}
+
 			fieldPointers = append(fieldPointers, modelValue.Field(idx).Addr().Interface())
 		}
 
@@ -299,21 +407,33 @@ type selectOneQuery struct {
 }
 
 func (q *selectOneQuery) Exec(db *sql.DB) error {
-	return exec(q, db)
+	return q.ExecContext(context.Background(), db)
+}
+
+func (q *selectOneQuery) ExecContext(ctx context.Context, db *sql.DB) error {
+	return q.run(ctx, db)
 }
 
 func (q *selectOneQuery) ExecTx(tx *sql.Tx) error {
-	stmt, err := tx.Prepare(q.str)
-	defer stmt.Close()
+	return q.ExecTxContext(context.Background(), tx)
+}
+
+func (q *selectOneQuery) ExecTxContext(ctx context.Context, tx *sql.Tx) error {
+	return q.run(ctx, tx)
+}
+
+func (q *selectOneQuery) run(ctx context.Context, p preparer) error {
+	stmt, err := p.PrepareContext(ctx, q.str)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
+	defer stmt.Close()
 
-	rows, err := stmt.Query(q.args...)
-	defer rows.Close()
+	rows, err := stmt.QueryContext(ctx, q.args...)
 	if err != nil {
 		return fmt.Errorf("failed to execute prepared statement: %w", err)
 	}
+	defer rows.Close()
 
 	if !rows.Next() {
 		return ErrModelNotFound
@@ -323,6 +443,10 @@ func (q *selectOneQuery) ExecTx(tx *sql.Tx) error {
 
 	fieldPointers := []interface{}{}
 	for idx := 0; idx < val.NumField(); idx++ {
+		if isSkipped(val.Type().Field(idx)) {
+			continue
+		}
+
 		fieldPointers = append(fieldPointers, val.Field(idx).Addr().Interface())
 	}
 
@@ -345,15 +469,27 @@ type insertQuery struct {
 }
 
 func (q *insertQuery) Exec(db *sql.DB) error {
-	return exec(q, db)
+	return q.ExecContext(context.Background(), db)
+}
+
+func (q *insertQuery) ExecContext(ctx context.Context, db *sql.DB) error {
+	return exec(ctx, q, db)
 }
 
 func (q *insertQuery) ExecTx(tx *sql.Tx) error {
+	return q.ExecTxContext(context.Background(), tx)
+}
+
+func (q *insertQuery) ExecTxContext(ctx context.Context, tx *sql.Tx) error {
 	modelVal := reflect.Indirect(reflect.ValueOf(q.model))
 
 	snakeFieldNames := []string{}
 	fieldValues := []interface{}{}
 	for idx := 0; idx < modelVal.NumField(); idx++ {
+		if isSkipped(modelVal.Type().Field(idx)) {
+			continue
+		}
+
 		// Caller shouldn't be modifying ID
 		if modelVal.Type().Field(idx).Name == "ID" {
 			continue
@@ -391,21 +527,20 @@ func (q *insertQuery) ExecTx(tx *sql.Tx) error {
 		modelVal.FieldByName("UpdatedAt").Set(reflect.ValueOf(now))
 	}
 
-	modelName := modelVal.Type().Name()
-	tableName := fmt.Sprintf("%ss", toSnake(modelName))
+	tableName := modelTable(q.model)
 	paramStrings := []string{}
 	for idx := 0; idx < len(snakeFieldNames); idx++ {
 		paramStrings = append(paramStrings, "?")
 	}
 	query := fmt.Sprintf("insert into %s (%s) values (%s)", tableName, strings.Join(snakeFieldNames, ","), strings.Join(paramStrings, ","))
 
-	stmt, err := tx.Prepare(query)
-	defer stmt.Close()
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(fieldValues...)
+	res, err := stmt.ExecContext(ctx, fieldValues...)
 	if err != nil {
 		return fmt.Errorf("failed to execute prepared statement: %w", err)
 	}
@@ -434,15 +569,27 @@ type updateQuery struct {
 }
 
 func (q *updateQuery) Exec(db *sql.DB) error {
-	return exec(q, db)
+	return q.ExecContext(context.Background(), db)
+}
+
+func (q *updateQuery) ExecContext(ctx context.Context, db *sql.DB) error {
+	return exec(ctx, q, db)
 }
 
 func (q *updateQuery) ExecTx(tx *sql.Tx) error {
+	return q.ExecTxContext(context.Background(), tx)
+}
+
+func (q *updateQuery) ExecTxContext(ctx context.Context, tx *sql.Tx) error {
 	modelVal := reflect.Indirect(reflect.ValueOf(q.model))
 
 	fieldNames := []string{}
 	fieldValues := []interface{}{}
 	for idx := 0; idx < modelVal.NumField(); idx++ {
+		if isSkipped(modelVal.Type().Field(idx)) {
+			continue
+		}
+
 		// Caller shouldn't be modifying ID
 		if modelVal.Type().Field(idx).Name == "ID" {
 			continue
@@ -474,21 +621,20 @@ func (q *updateQuery) ExecTx(tx *sql.Tx) error {
 
 	fieldValues = append(fieldValues, modelVal.FieldByName("ID").Interface())
 
-	modelName := modelVal.Type().Name()
-	tableName := fmt.Sprintf("%ss", toSnake(modelName))
+	tableName := modelTable(q.model)
 	paramStrings := []string{}
 	for _, fieldName := range fieldNames {
 		paramStrings = append(paramStrings, fmt.Sprintf("%s=?", toSnake(fieldName)))
 	}
 	query := fmt.Sprintf("update %s set %s where id=?", tableName, strings.Join(paramStrings, ","))
 
-	stmt, err := tx.Prepare(query)
-	defer stmt.Close()
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(fieldValues...)
+	res, err := stmt.ExecContext(ctx, fieldValues...)
 	if err != nil {
 		return fmt.Errorf("failed to execute prepared statement: %w", err)
 	}
@@ -510,15 +656,23 @@ type upsertQuery struct {
 }
 
 func (q *upsertQuery) Exec(db *sql.DB) error {
-	return exec(q, db)
+	return q.ExecContext(context.Background(), db)
+}
+
+func (q *upsertQuery) ExecContext(ctx context.Context, db *sql.DB) error {
+	return exec(ctx, q, db)
 }
 
 func (q *upsertQuery) ExecTx(tx *sql.Tx) error {
+	return q.ExecTxContext(context.Background(), tx)
+}
+
+func (q *upsertQuery) ExecTxContext(ctx context.Context, tx *sql.Tx) error {
 	var count int
 	modelVal := reflect.Indirect(reflect.ValueOf(q.model))
 	selectCountFromQuery := SelectCountFrom(modelTable(q.model), &count, clause.Where("id = ?", modelVal.FieldByName("ID").Interface()))
 
-	err := selectCountFromQuery.ExecTx(tx)
+	err := selectCountFromQuery.ExecTxContext(ctx, tx)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -534,7 +688,7 @@ func (q *upsertQuery) ExecTx(tx *sql.Tx) error {
 		return fmt.Errorf("failed to create query: %w", err)
 	}
 
-	err = query.ExecTx(tx)
+	err = query.ExecTxContext(ctx, tx)
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -560,6 +714,24 @@ func Delete(models interface{}) (Query, error) {
 	return &query, nil
 }
 
+// DeleteWhere returns a delete query which deletes the models matching the given query clauses from the table of the given model
+func DeleteWhere(model interface{}, clauses ...*clause.Clause) (Query, error) {
+	var query deleteWhereQuery
+
+	if !isModel(model) {
+		return &query, ErrInvalidModelArg
+	}
+
+	if !modelTypeHasID(model) {
+		return &query, ErrMissingIdField
+	}
+
+	query.str = fmt.Sprintf("delete from %s", modelTable(model))
+	query.addAll(clauses...)
+
+	return &query, nil
+}
+
 // SelectCountFrom returns a select query which fetches the number of records in the given table and assigns the result to the given reference, subject to the given query clauses
 func SelectCountFrom(table string, result *int, clauses ...*clause.Clause) Query {
 	var query selectCountQuery
@@ -588,6 +760,10 @@ func Select(results interface{}, clauses ...*clause.Clause) (Query, error) {
 
 	snakeFieldNames := []string{}
 	for idx := 0; idx < modelsType.NumField(); idx++ {
+		if isSkipped(modelsType.Field(idx)) {
+			continue
+		}
+
 		snakeFieldNames = append(
 			snakeFieldNames,
 			fmt.Sprintf("%s.%s", tableName, toSnake(modelsType.Field(idx).Name)))
@@ -618,6 +794,10 @@ func SelectOne(result interface{}, clauses ...*clause.Clause) (Query, error) {
 
 	snakeFieldNames := []string{}
 	for idx := 0; idx < modelType.NumField(); idx++ {
+		if isSkipped(modelType.Field(idx)) {
+			continue
+		}
+
 		snakeFieldNames = append(
 			snakeFieldNames,
 			fmt.Sprintf("%s.%s", tableName, toSnake(modelType.Field(idx).Name)))
@@ -36,6 +36,23 @@ type ManagedFieldsModel struct {
 	UpdatedAt time.Time
 }
 
+type Status struct {
+	ID     uint
+	String string
+}
+
+type Category struct {
+	ID     uint
+	String string
+}
+
+type SkippedFieldsModel struct {
+	ID     uint
+	String string
+	Models []*Model `orm:"-"`
+	Bool   bool
+}
+
 func InitDB(t *testing.T) *sql.DB {
 	path := fmt.Sprintf(
 		"/tmp/gonews/test/%d/db.sqlite3",
@@ -64,6 +81,9 @@ createDB:
 	CreateModelsTable(t, db)
 	CreateManagedFieldsModelsTable(t, db)
 	CreateSecondaryModelsTable(t, db)
+	CreateStatusesTable(t, db)
+	CreateCategoriesTable(t, db)
+	CreateSkippedFieldsModelsTable(t, db)
 
 	return db
 }
@@ -83,6 +103,21 @@ func CreateSecondaryModelsTable(t *testing.T, db *sql.DB) {
 	assert.NoError(t, err)
 }
 
+func CreateStatusesTable(t *testing.T, db *sql.DB) {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS \"statuses\" (\"id\" integer primary key autoincrement,\"string\" varchar(255))")
+	assert.NoError(t, err)
+}
+
+func CreateCategoriesTable(t *testing.T, db *sql.DB) {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS \"categories\" (\"id\" integer primary key autoincrement,\"string\" varchar(255))")
+	assert.NoError(t, err)
+}
+
+func CreateSkippedFieldsModelsTable(t *testing.T, db *sql.DB) {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS \"skipped_fields_models\" (\"id\" integer primary key autoincrement,\"string\" varchar(255),\"bool\" bool);")
+	assert.NoError(t, err)
+}
+
 func AssertModelsEqual(t *testing.T, m1, m2 *Model) {
 	assert.Equal(t, m1.Bool, m2.Bool)
 	assert.Equal(t, m1.String, m2.String)
@@ -0,0 +1,91 @@
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// Query parameters added to links for tracking, which don't change the page
+// they point to
+var (
+	trackingParamPrefixes = []string{"utm_"}
+	trackingParams        = map[string]bool{
+		"fbclid": true,
+		"gclid":  true,
+		"igshid": true,
+		"mc_cid": true,
+		"mc_eid": true,
+	}
+)
+
+func isTrackingParam(param string) bool {
+	param = strings.ToLower(param)
+	for _, prefix := range trackingParamPrefixes {
+		if strings.HasPrefix(param, prefix) {
+			return true
+		}
+	}
+
+	return trackingParams[param]
+}
+
+// CanonicalLink normalizes the given link, so that links to the same page
+// compare equal; the scheme & host are lowercased, default ports are removed,
+// and tracking parameters are stripped from the query
+// Links which can't be parsed are returned unchanged
+func CanonicalLink(link string) string {
+	link = strings.TrimSpace(link)
+
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+
+	// The query is only re-encoded if a parameter is removed, since
+	// encoding reorders the remaining parameters
+	query := u.Query()
+	stripped := false
+	for param := range query {
+		if isTrackingParam(param) {
+			query.Del(param)
+			stripped = true
+		}
+	}
+
+	if stripped {
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+func normalizeContent(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// ContentHash returns a hash of the given title & description, normalized so
+// that whitespace & case changes don't affect the hash, or an empty string if
+// there's no content
+// The unescaped values are expected, rather than those stored in the item
+func ContentHash(title, description string) string {
+	title = normalizeContent(title)
+	description = normalizeContent(description)
+	if title == "" && description == "" {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write([]byte(title))
+	h.Write([]byte{0})
+	h.Write([]byte(description))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
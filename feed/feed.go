@@ -3,20 +3,126 @@ package feed
 import (
 	"fmt"
 	"html"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mmcdole/gofeed"
 )
 
 // Feed contains the data associated with a feed stored in the database
+// ETag & LastModified are the HTTP cache validators returned by the most
+// recent fetch, sent back on the next request so the server can skip
+// unchanged feeds
+// FetchPeriod is the configured polling period; if zero, the period adapts to
+// how often the feed publishes
+// If FullText is set, the content of new items is extracted from their links,
+// for feeds which only publish teasers
+// Name is the configured name of the feed, if any; Title, Link, Description,
+// Image & Language are the feed's metadata from its most recent fetch
+// PurgedAt is when items were last deleted from the feed by its retention
+// policy, if ever
 type Feed struct {
-	ID         uint
-	URL        string
-	FetchLimit uint
+	ID           uint
+	URL          string
+	FetchLimit   uint
+	ETag         string
+	LastModified string
+	FetchPeriod  time.Duration
+	NextFetchAt  time.Time
+	FullText     bool
+	Name         string
+	Title        string
+	Link         string
+	Description  string
+	Image        string
+	Language     string
+	PurgedAt     time.Time
 }
 
 func (f Feed) String() string {
-	return fmt.Sprintf("Feed{URL: %s, FetchLimit: %d}", f.URL, f.FetchLimit)
+	return fmt.Sprintf("Feed{URL: %s, FetchLimit: %d, Title: %s}", f.URL, f.FetchLimit, f.Title)
+}
+
+// DisplayName returns the feed's configured name, or else its title, or else
+// its URL, escaped like the other text fields
+func (f Feed) DisplayName() string {
+	if f.Name != "" {
+		return html.EscapeString(f.Name)
+	}
+	if f.Title != "" {
+		return f.Title
+	}
+
+	return html.EscapeString(f.URL)
+}
+
+// SetMetadata overrides the feed's metadata with the given metadata
+func (f *Feed) SetMetadata(m *Metadata) {
+	f.Title = m.Title
+	f.Link = m.Link
+	f.Description = m.Description
+	f.Image = m.Image
+	f.Language = m.Language
+}
+
+// Metadata contains the fields which describe a feed, as opposed to its items
+type Metadata struct {
+	Title       string
+	Link        string
+	Description string
+	Image       string
+	Language    string
+}
+
+// MetadataFromGofeed returns the metadata of the given gofeed feed
+// The text fields are escaped, and the links sanitized like those in item
+// descriptions, resolving them against the given base URL of the feed, if any
+func MetadataFromGofeed(gfeed *gofeed.Feed, base *url.URL) *Metadata {
+	m := &Metadata{
+		Title:       html.EscapeString(strings.TrimSpace(gfeed.Title)),
+		Description: html.EscapeString(strings.TrimSpace(gfeed.Description)),
+		Language:    html.EscapeString(strings.TrimSpace(gfeed.Language)),
+	}
+
+	// An empty URL would resolve to the base URL
+	if strings.TrimSpace(gfeed.Link) != "" {
+		m.Link = html.EscapeString(sanitizeURL("a", gfeed.Link, base))
+	}
+	if gfeed.Image != nil && strings.TrimSpace(gfeed.Image.URL) != "" {
+		m.Image = html.EscapeString(sanitizeURL("img", gfeed.Image.URL, base))
+	}
+
+	return m
+}
+
+// Status contains the health of a feed, as of its most recent fetch, stored in
+// the database
+type Status struct {
+	ID                  uint      `json:"id"`
+	FeedID              uint      `json:"feed_id"`
+	LastSuccessAt       time.Time `json:"last_success_at"`
+	LastErrorAt         time.Time `json:"last_error_at"`
+	LastError           string    `json:"last_error"`
+	ConsecutiveFailures uint      `json:"consecutive_failures"`
+	LastHTTPStatus      int       `json:"last_http_status"`
+}
+
+func (s Status) String() string {
+	return fmt.Sprintf(
+		"Status{FeedID: %d, LastSuccessAt: %s, LastErrorAt: %s, LastError: %s, ConsecutiveFailures: %d, LastHTTPStatus: %d}",
+		s.FeedID,
+		s.LastSuccessAt,
+		s.LastErrorAt,
+		s.LastError,
+		s.ConsecutiveFailures,
+		s.LastHTTPStatus)
+}
+
+// Healthy returns false if the most recent fetch of the feed failed
+func (s Status) Healthy() bool {
+	return s.ConsecutiveFailures == 0
 }
 
 // Tag contains the data associated with a feed tag stored in the database
@@ -35,17 +141,38 @@ func (t Tag) String() string {
 // Item contains the data associated with a feed item stored in the database
 // Some fields copied from gofeed.Item; couldn't embed gofeed.Item because it
 // includes slices, which can't be directly saved to the DB
+// GUID is the entry's unique ID from the feed, if any, and Hash is the
+// ContentHash of its title & description; both are used to recognize items
+// which have already been saved
+// RevisedAt is when the feed last changed the item's content, if ever
+// Content is the full content of the item from the feed or, for full-text
+// feeds, the article extracted from its link
+// Name & Email are those of the item's first author, and Authors contains any
+// others
+// Categories, Enclosures & Authors are stored in their own tables, and FeedName
+// is the display name of the item's feed; they're only set when loaded
+// explicitly
 type Item struct {
 	ID          uint
 	Name        string
 	Email       string
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Link        string    `json:"link"`
-	Published   time.Time `json:"time"`
-	Hide        bool      `json:"hide"`
-	FeedID      uint      `json:"feed_id"`
-	CreatedAt   time.Time `json:"created_at"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Link        string          `json:"link"`
+	GUID        string          `json:"guid"`
+	Hash        string          `json:"hash"`
+	RevisedAt   time.Time       `json:"revised_at"`
+	Content     string          `json:"content"`
+	Image       string          `json:"image"`
+	Updated     time.Time       `json:"updated"`
+	Categories  []*ItemCategory `json:"categories" orm:"-"`
+	Enclosures  []*Enclosure    `json:"enclosures" orm:"-"`
+	Authors     []*ItemAuthor   `json:"authors" orm:"-"`
+	Published   time.Time       `json:"time"`
+	Hide        bool            `json:"hide"`
+	FeedID      uint            `json:"feed_id"`
+	FeedName    string          `json:"feed_name" orm:"-"`
+	CreatedAt   time.Time       `json:"created_at"`
 }
 
 func (i Item) String() string {
@@ -68,9 +195,140 @@ func (i Item) String() string {
 		i.FeedID)
 }
 
+// ItemCategory contains a category, or tag, which the feed assigned to an item
+type ItemCategory struct {
+	ID     uint   `json:"id"`
+	ItemID uint   `json:"item_id"`
+	Name   string `json:"name"`
+}
+
+func (c ItemCategory) String() string {
+	return fmt.Sprintf("ItemCategory{ItemID: %d, Name: %s}", c.ItemID, c.Name)
+}
+
+// ItemAuthor contains an author of an item other than the first, whose name &
+// email are stored in the item itself
+type ItemAuthor struct {
+	ID     uint   `json:"id"`
+	ItemID uint   `json:"item_id"`
+	Name   string `json:"name"`
+}
+
+func (a ItemAuthor) String() string {
+	return fmt.Sprintf("ItemAuthor{ItemID: %d, Name: %s}", a.ItemID, a.Name)
+}
+
+// Enclosure contains a file attached to an item, ex. a podcast episode
+// Length is the size of the file in bytes, if known
+type Enclosure struct {
+	ID     uint   `json:"id"`
+	ItemID uint   `json:"item_id"`
+	URL    string `json:"url"`
+	Length int64  `json:"length"`
+	Type   string `json:"type"`
+}
+
+func (e Enclosure) String() string {
+	return fmt.Sprintf(
+		"Enclosure{ItemID: %d, URL: %s, Length: %d, Type: %s}",
+		e.ItemID,
+		e.URL,
+		e.Length,
+		e.Type)
+}
+
+// ItemRevision contains a previous version of an item's content, stored in the
+// database when the feed changes the item
+type ItemRevision struct {
+	ID          uint      `json:"id"`
+	ItemID      uint      `json:"item_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Link        string    `json:"link"`
+	Content     string    `json:"content"`
+	Hash        string    `json:"hash"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (r ItemRevision) String() string {
+	return fmt.Sprintf(
+		"ItemRevision{ItemID: %d, Title: %s, Description: %s, CreatedAt: %s}",
+		r.ItemID,
+		r.Title,
+		r.Description,
+		r.CreatedAt)
+}
+
+// Tombstone contains what identifies an item which was deleted by its feed's
+// retention policy, stored in the database, so that the item isn't inserted
+// again while the feed still contains it
+type Tombstone struct {
+	ID        uint
+	FeedID    uint
+	GUID      string
+	Link      string
+	Hash      string
+	CreatedAt time.Time
+}
+
+func (t Tombstone) String() string {
+	return fmt.Sprintf(
+		"Tombstone{FeedID: %d, GUID: %s, Link: %s, Hash: %s}",
+		t.FeedID,
+		t.GUID,
+		t.Link,
+		t.Hash)
+}
+
+// Icon contains the icon of a feed, stored in the database; the icon itself is
+// stored in the data directory
+// ContentType is empty if no icon has been found for the feed; FetchedAt is
+// when the icon was last fetched, or attempted
+type Icon struct {
+	ID          uint
+	FeedID      uint
+	URL         string
+	ContentType string
+	FetchedAt   time.Time
+}
+
+func (i Icon) String() string {
+	return fmt.Sprintf(
+		"Icon{FeedID: %d, URL: %s, ContentType: %s, FetchedAt: %s}",
+		i.FeedID,
+		i.URL,
+		i.ContentType,
+		i.FetchedAt)
+}
+
+// Alert contains a match of a watchlist in a new item, stored in the database
+// Term is the watchlist's term or regex which matched, and Notified is set
+// once the alert is sent to the notifiers
+type Alert struct {
+	ID        uint      `json:"id"`
+	ItemID    uint      `json:"item_id"`
+	FeedID    uint      `json:"feed_id"`
+	Watchlist string    `json:"watchlist"`
+	Term      string    `json:"term"`
+	Notified  bool      `json:"notified"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf(
+		"Alert{ItemID: %d, FeedID: %d, Watchlist: %s, Term: %s, Notified: %t}",
+		a.ItemID,
+		a.FeedID,
+		a.Watchlist,
+		a.Term,
+		a.Notified)
+}
+
 // FromGofeedItem overrides the fields in the item with those from the given
 // gofeed item
-func (i *Item) FromGofeedItem(gfi *gofeed.Item) error {
+// The description & content are sanitized, resolving relative URLs against the
+// given base URL of the feed, if any; other text fields are escaped
+func (i *Item) FromGofeedItem(gfi *gofeed.Item, base *url.URL) error {
 	if i == nil || gfi == nil {
 		return fmt.Errorf("item pointer is nil")
 	}
@@ -82,17 +340,90 @@ func (i *Item) FromGofeedItem(gfi *gofeed.Item) error {
 		email = html.EscapeString(gfi.Author.Email)
 	}
 
+	// gofeed items only have one author, so any others are taken from the
+	// Dublin Core creators, which the parser also maps Atom & JSON Feed
+	// authors to
+	var authors []*ItemAuthor
+	if gfi.DublinCoreExt != nil {
+		seen := map[string]bool{name: true}
+		for _, creator := range gfi.DublinCoreExt.Creator {
+			creator = html.EscapeString(strings.TrimSpace(creator))
+			if creator == "" || seen[creator] {
+				continue
+			}
+			seen[creator] = true
+
+			authors = append(authors, &ItemAuthor{
+				Name: creator,
+			})
+		}
+	}
+
 	var published time.Time
 	if gfi.PublishedParsed != nil {
 		published = *gfi.PublishedParsed
 	}
 
+	var updated time.Time
+	if gfi.UpdatedParsed != nil {
+		updated = *gfi.UpdatedParsed
+	}
+
+	// Links are resolved & checked the same way as those in the content,
+	// since they're rendered as links too
+	var link string
+	if strings.TrimSpace(gfi.Link) != "" {
+		link = html.EscapeString(CanonicalLink(sanitizeURL("a", gfi.Link, base)))
+	}
+
+	var image string
+	if gfi.Image != nil && strings.TrimSpace(gfi.Image.URL) != "" {
+		image = html.EscapeString(sanitizeURL("img", gfi.Image.URL, base))
+	}
+
+	var categories []*ItemCategory
+	for _, name := range gfi.Categories {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		categories = append(categories, &ItemCategory{
+			Name: html.EscapeString(name),
+		})
+	}
+
+	var enclosures []*Enclosure
+	for _, gfe := range gfi.Enclosures {
+		if gfe == nil || gfe.URL == "" {
+			continue
+		}
+
+		// The length is often missing or invalid, in which case it's
+		// left unknown
+		length, _ := strconv.ParseInt(strings.TrimSpace(gfe.Length), 10, 64)
+
+		enclosures = append(enclosures, &Enclosure{
+			URL:    html.EscapeString(gfe.URL),
+			Length: length,
+			Type:   html.EscapeString(gfe.Type),
+		})
+	}
+
 	i.Name = name
 	i.Email = email
 	i.Title = html.EscapeString(gfi.Title)
-	i.Description = html.EscapeString(gfi.Description)
-	i.Link = html.EscapeString(gfi.Link)
+	i.Description = Sanitize(gfi.Description, base)
+	i.Link = link
+	i.GUID = strings.TrimSpace(gfi.GUID)
+	i.Hash = ContentHash(gfi.Title, gfi.Description)
 	i.Published = published
+	i.Content = Sanitize(gfi.Content, base)
+	i.Image = image
+	i.Updated = updated
+	i.Categories = categories
+	i.Enclosures = enclosures
+	i.Authors = authors
 
 	return nil
 }
@@ -0,0 +1,151 @@
+package feed
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalLink(t *testing.T) {
+	assert.Equal(t,
+		"https://example.com/post?id=1",
+		CanonicalLink(" HTTPS://Example.com:443/post?id=1&utm_source=rss&utm_medium=feed "))
+	assert.Equal(t,
+		"http://example.com/post",
+		CanonicalLink("http://example.com:80/post?fbclid=abc"))
+	assert.Equal(t,
+		"https://example.com:8443/post?b=2&a=1#comments",
+		CanonicalLink("https://example.com:8443/post?b=2&a=1#comments"))
+	assert.Equal(t, "not a url", CanonicalLink("not a url"))
+}
+
+func TestContentHash(t *testing.T) {
+	assert.Equal(t,
+		ContentHash("Title", "Some description"),
+		ContentHash(" title ", "some\n  Description"))
+	assert.NotEqual(t,
+		ContentHash("Title", "Some description"),
+		ContentHash("Title", "Other description"))
+	assert.NotEqual(t,
+		ContentHash("Title description", ""),
+		ContentHash("Title", "description"))
+	assert.Empty(t, ContentHash(" ", ""))
+}
+
+func TestFromGofeedItem(t *testing.T) {
+	var i Item
+	err := i.FromGofeedItem(&gofeed.Item{
+		Title:       "<b>Title</b>",
+		Description: "Description",
+		Link:        "https://example.com/post?a=1&utm_source=rss",
+		GUID:        " guid ",
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "&lt;b&gt;Title&lt;/b&gt;", i.Title)
+	assert.Equal(t, "https://example.com/post?a=1", i.Link)
+	assert.Equal(t, "guid", i.GUID)
+	assert.Equal(t, ContentHash("<b>Title</b>", "Description"), i.Hash)
+}
+
+func TestFromGofeedItemChecksLinkSchemes(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/")
+	assert.NoError(t, err)
+
+	var i Item
+	err = i.FromGofeedItem(&gofeed.Item{
+		Title: "Title",
+		Link:  "post",
+		Image: &gofeed.Image{URL: "image.png"},
+	}, base)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/blog/post", i.Link)
+	assert.Equal(t, "https://example.com/blog/image.png", i.Image)
+
+	err = i.FromGofeedItem(&gofeed.Item{
+		Title: "Title",
+		Link:  " javascript:alert(1)",
+		Image: &gofeed.Image{URL: "data:image/svg+xml,<svg></svg>"},
+	}, base)
+	assert.NoError(t, err)
+	assert.Empty(t, i.Link)
+	assert.Empty(t, i.Image)
+}
+
+func TestFromGofeedItemCopiesContentCategoriesAndEnclosures(t *testing.T) {
+	var i Item
+	err := i.FromGofeedItem(&gofeed.Item{
+		Title:      "Title",
+		Content:    "<p>Content</p>",
+		Image:      &gofeed.Image{URL: "https://example.com/image.png"},
+		Categories: []string{"security", ""},
+		Enclosures: []*gofeed.Enclosure{
+			{URL: "https://example.com/episode.mp3", Length: "1024", Type: "audio/mpeg"},
+			{URL: "https://example.com/episode.ogg", Length: "unknown", Type: "audio/ogg"},
+			{URL: ""},
+		},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Content</p>", i.Content)
+	assert.Equal(t, "https://example.com/image.png", i.Image)
+	assert.Equal(t, []*ItemCategory{{Name: "security"}}, i.Categories)
+	assert.Equal(t, []*Enclosure{
+		{URL: "https://example.com/episode.mp3", Length: 1024, Type: "audio/mpeg"},
+		{URL: "https://example.com/episode.ogg", Type: "audio/ogg"},
+	}, i.Enclosures)
+}
+
+func TestFromGofeedItemKeepsExtraAuthors(t *testing.T) {
+	var i Item
+	err := i.FromGofeedItem(&gofeed.Item{
+		Title:  "Title",
+		Author: &gofeed.Person{Name: "Alice", Email: "alice@example.com"},
+		DublinCoreExt: &ext.DublinCoreExtension{
+			Creator: []string{"Alice", "Bob", " ", "Bob", "<Carol>"},
+		},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", i.Name)
+	assert.Equal(t, "alice@example.com", i.Email)
+	assert.Equal(t, []*ItemAuthor{{Name: "Bob"}, {Name: "&lt;Carol&gt;"}}, i.Authors)
+}
+
+func TestMetadataFromGofeed(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/")
+	assert.NoError(t, err)
+
+	m := MetadataFromGofeed(&gofeed.Feed{
+		Title:       " News & Views ",
+		Link:        "/blog/",
+		Description: "<b>Daily</b>",
+		Image:       &gofeed.Image{URL: "logo.png?size=1&dpi=2"},
+		Language:    "en-us",
+	}, base)
+	assert.Equal(t, &Metadata{
+		Title:       "News &amp; Views",
+		Link:        "https://example.com/blog/",
+		Description: "&lt;b&gt;Daily&lt;/b&gt;",
+		Image:       "https://example.com/blog/logo.png?size=1&amp;dpi=2",
+		Language:    "en-us",
+	}, m)
+
+	m = MetadataFromGofeed(&gofeed.Feed{
+		Link:  "javascript:alert(1)",
+		Image: &gofeed.Image{},
+	}, base)
+	assert.Empty(t, m.Link)
+	assert.Empty(t, m.Image)
+}
+
+func TestDisplayName(t *testing.T) {
+	f := Feed{URL: "https://example.com/feed?a=1&b=2"}
+	assert.Equal(t, "https://example.com/feed?a=1&amp;b=2", f.DisplayName())
+
+	f.Title = "News &amp; Views"
+	assert.Equal(t, "News &amp; Views", f.DisplayName())
+
+	f.Name = "N&V"
+	assert.Equal(t, "N&amp;V", f.DisplayName())
+}
@@ -0,0 +1,218 @@
+package feed
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Tags which are kept by Sanitize, with the attributes allowed on each
+// Any other tag is removed, but its text is kept
+var allowedTags = map[string][]string{
+	"a":          {"href", "title"},
+	"b":          nil,
+	"blockquote": nil,
+	"br":         nil,
+	"code":       nil,
+	"em":         nil,
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"i":          nil,
+	"img":        {"src", "alt", "title", "width", "height"},
+	"li":         nil,
+	"ol":         nil,
+	"p":          nil,
+	"pre":        nil,
+	"strong":     nil,
+	"ul":         nil,
+}
+
+// Tags which are removed along with their content
+var droppedTags = map[string]bool{
+	"embed":    true,
+	"head":     true,
+	"iframe":   true,
+	"math":     true,
+	"noscript": true,
+	"object":   true,
+	"script":   true,
+	"select":   true,
+	"style":    true,
+	"svg":      true,
+	"template": true,
+	"textarea": true,
+	"title":    true,
+}
+
+// Tags which have no content, and so no end tag
+var voidTags = map[string]bool{
+	"br":  true,
+	"img": true,
+}
+
+// Schemes allowed in the URL attributes of each tag
+var allowedSchemes = map[string][]string{
+	"a":   {"http", "https", "mailto"},
+	"img": {"http", "https"},
+}
+
+// Resolve the URL against the base URL, returning "" if it can't be parsed or
+// its scheme isn't allowed, ex. javascript: URLs
+func sanitizeURL(tag, rawURL string, base *url.URL) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	for _, allowed := range allowedSchemes[tag] {
+		if scheme == allowed {
+			return u.String()
+		}
+	}
+
+	return ""
+}
+
+// Return the start tag with only its allowed attributes, or false if the tag
+// should be removed, ex. an image without a valid source
+func sanitizeTag(token html.Token, base *url.URL) (string, bool) {
+	var b strings.Builder
+	b.WriteString("<" + token.Data)
+
+	var hasSrc bool
+	for _, attr := range token.Attr {
+		if attr.Namespace != "" || !isAllowedAttr(token.Data, attr.Key) {
+			continue
+		}
+
+		val := attr.Val
+		if attr.Key == "href" || attr.Key == "src" {
+			val = sanitizeURL(token.Data, val, base)
+			if val == "" {
+				continue
+			}
+		}
+		if attr.Key == "src" {
+			hasSrc = true
+		}
+
+		b.WriteString(" " + attr.Key + `="` + html.EscapeString(val) + `"`)
+	}
+
+	if token.Data == "img" && !hasSrc {
+		return "", false
+	}
+
+	b.WriteString(">")
+
+	return b.String(), true
+}
+
+func isAllowedAttr(tag, key string) bool {
+	for _, allowed := range allowedTags[tag] {
+		if key == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sanitize the HTML fragment, keeping only an allowlist of formatting tags &
+// attributes, so that it's safe to display
+// Scripts, styles, event handlers & URLs other than http(s) are removed, and
+// relative URLs are resolved against the base URL, if given; unclosed tags are
+// closed, and text is escaped
+func Sanitize(fragment string, base *url.URL) string {
+	var b strings.Builder
+	var open []string
+	var dropped []string
+
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		// The end of the fragment, or a read error, which can't happen
+		// with a strings.Reader
+		if tt == html.ErrorToken {
+			break
+		}
+
+		token := z.Token()
+		tag := token.Data
+
+		// Skip everything inside a dropped element, tracking nested
+		// elements of the same kind
+		if len(dropped) > 0 {
+			switch {
+			case tt == html.StartTagToken && droppedTags[tag]:
+				dropped = append(dropped, tag)
+			case tt == html.EndTagToken && tag == dropped[len(dropped)-1]:
+				dropped = dropped[:len(dropped)-1]
+			}
+			continue
+		}
+
+		switch tt {
+		case html.TextToken:
+			b.WriteString(html.EscapeString(token.Data))
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[tag] {
+				if tt == html.StartTagToken {
+					dropped = append(dropped, tag)
+				}
+				continue
+			}
+
+			if _, ok := allowedTags[tag]; !ok {
+				continue
+			}
+
+			start, ok := sanitizeTag(token, base)
+			if !ok {
+				continue
+			}
+			b.WriteString(start)
+
+			if voidTags[tag] {
+				continue
+			}
+			if tt == html.SelfClosingTagToken {
+				b.WriteString("</" + tag + ">")
+				continue
+			}
+			open = append(open, tag)
+
+		case html.EndTagToken:
+			// Close any elements left open inside this one; end tags
+			// which don't match an open element are ignored
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != tag {
+					continue
+				}
+
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package feed
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeKeepsAllowedTags(t *testing.T) {
+	fragment := `<p>Some <em>emphasis</em>, <strong>bold</strong> &amp; <code>code</code></p>` +
+		`<pre>x &lt; y</pre><ul><li>One</li><li>Two</li></ul>` +
+		`<a href="https://example.com/" title="Example">link</a>` +
+		`<img src="https://example.com/image.png" alt="Image">`
+	assert.Equal(t, fragment, Sanitize(fragment, nil))
+
+	// Self-closing tags are normalized
+	assert.Equal(t, "<p>One<br>Two</p><p></p>", Sanitize("<p>One<br/>Two</p><p/>", nil))
+}
+
+func TestSanitizeRemovesUnsafeMarkup(t *testing.T) {
+	tests := []struct {
+		fragment string
+		expected string
+	}{
+		{`<script>alert(1)</script>Text`, "Text"},
+		{`<style>p { color: red }</style><p>Text</p>`, "<p>Text</p>"},
+		{`<p onclick="alert(1)" style="color: red" class="x">Text</p>`, "<p>Text</p>"},
+		{`<a href="javascript:alert(1)">Text</a>`, "<a>Text</a>"},
+		{`<a href=" JavaScript:alert(1)">Text</a>`, "<a>Text</a>"},
+		{`<img src="javascript:alert(1)" onerror="alert(1)">`, ""},
+		{`<img src="data:image/png;base64,AAAA">`, ""},
+		{`<iframe src="https://example.com/"><p>Nested</p></iframe>After`, "After"},
+		{`<svg><svg></svg><script>alert(1)</script></svg>After`, "After"},
+		{`<div><span>Text</span></div>`, "Text"},
+		{`<!-- comment --><p>Text`, "<p>Text</p>"},
+		{`<ul><li>One</ul></li></p>`, "<ul><li>One</li></ul>"},
+		{`1 < 2 & "quoted"`, "1 &lt; 2 &amp; &#34;quoted&#34;"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Sanitize(test.fragment, nil), test.fragment)
+	}
+}
+
+func TestSanitizeResolvesRelativeURLs(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/")
+	assert.NoError(t, err)
+
+	assert.Equal(t,
+		`<a href="https://example.com/blog/post">Post</a>`+
+			`<img src="https://example.com/image.png">`+
+			`<a href="https://other.example.com/">Other</a>`+
+			`<a href="mailto:me@example.com">Mail</a>`,
+		Sanitize(`<a href="post">Post</a>`+
+			`<img src="/image.png">`+
+			`<a href="//other.example.com/">Other</a>`+
+			`<a href="mailto:me@example.com">Mail</a>`, base))
+
+	// Relative URLs can't be resolved without a base
+	assert.Equal(t, `<a>Post</a>`, Sanitize(`<a href="post">Post</a>`, nil))
+}
+
+func TestFromGofeedItemSanitizesDescription(t *testing.T) {
+	base, err := url.Parse("https://example.com/")
+	assert.NoError(t, err)
+
+	var i Item
+	err = i.FromGofeedItem(&gofeed.Item{
+		Title:       "Title",
+		Description: `<p>Read <a href="/more" onclick="track()">more</a></p><script>track()</script>`,
+	}, base)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>Read <a href="https://example.com/more">more</a></p>`, i.Description)
+}
@@ -3,6 +3,8 @@ module gonews
 go 1.13
 
 require (
+	github.com/PuerkitoBio/goquery v1.5.1
+	github.com/andybalholm/cascadia v1.1.0
 	github.com/go-delve/delve v1.6.0
 	github.com/go-sql-driver/mysql v1.5.0 // indirect
 	github.com/golang/mock v1.4.4
@@ -18,4 +20,5 @@ require (
 	github.com/ulule/limiter v2.2.2+incompatible
 	github.com/ziutek/mymysql v1.5.4 // indirect
 	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a
 )
@@ -0,0 +1,243 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"gonews/config"
+	"gonews/db"
+	"gonews/db/orm/query/clause"
+	"gonews/feed"
+	"gonews/notify"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Period between sending new alerts to the notifiers
+	alertsNotifyPeriod = 30 * time.Second
+
+	// Maximum number of alerts returned by Alerts by default
+	defaultAlertsLimit = 100
+)
+
+// watchlist is a watchlist compiled from its config
+type watchlist struct {
+	config  *config.WatchlistConfig
+	terms   []string
+	regexes []*regexp.Regexp
+}
+
+func compileWatchlists(wcs []*config.WatchlistConfig) ([]*watchlist, error) {
+	var watchlists []*watchlist
+	for _, wc := range wcs {
+		w := &watchlist{config: wc}
+		for _, term := range wc.Terms {
+			w.terms = append(w.terms, strings.ToLower(term))
+		}
+
+		for _, r := range wc.Regexes {
+			regex, err := regexp.Compile(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile watchlist regex: %w", err)
+			}
+			w.regexes = append(w.regexes, regex)
+		}
+
+		watchlists = append(watchlists, w)
+	}
+
+	return watchlists, nil
+}
+
+// Return the unescaped text of the item's title, description & content, which
+// watchlists are matched against
+func watchlistText(item *feed.Item) string {
+	return html.UnescapeString(strings.Join([]string{
+		item.Title,
+		htmlTagPattern.ReplaceAllString(item.Description, " "),
+		htmlTagPattern.ReplaceAllString(item.Content, " "),
+	}, "\n"))
+}
+
+// Return the first of the watchlist's terms or regexes which matches the text,
+// or an empty string if none do
+func (w *watchlist) match(text string) string {
+	lowerText := strings.ToLower(text)
+	for i, term := range w.terms {
+		if strings.Contains(lowerText, term) {
+			return w.config.Terms[i]
+		}
+	}
+
+	for i, regex := range w.regexes {
+		if regex.MatchString(text) {
+			return w.config.Regexes[i]
+		}
+	}
+
+	return ""
+}
+
+// Save an alert for each of the watchlists which match the newly inserted item
+func saveAlerts(ctx context.Context, db db.Tx, watchlists []*watchlist, item *feed.Item) error {
+	if len(watchlists) == 0 {
+		return nil
+	}
+
+	text := watchlistText(item)
+	for _, w := range watchlists {
+		term := w.match(text)
+		if term == "" {
+			continue
+		}
+
+		alert := &feed.Alert{
+			ItemID:    item.ID,
+			FeedID:    item.FeedID,
+			Watchlist: w.config.Name,
+			Term:      term,
+		}
+		err := db.SaveContext(ctx, alert)
+		if err != nil {
+			return fmt.Errorf("failed to save alert: %w", err)
+		}
+
+		log.Info().Msgf("alert: %s", alert)
+	}
+
+	return nil
+}
+
+// AlertItem contains an alert & the item which raised it
+type AlertItem struct {
+	Alert *feed.Alert `json:"alert"`
+	Item  *feed.Item  `json:"item"`
+}
+
+// Return the items with the given IDs, by ID
+func itemsByID(ctx context.Context, db db.DB, ids []interface{}) (map[uint]*feed.Item, error) {
+	itemMap := make(map[uint]*feed.Item)
+	if len(ids) == 0 {
+		return itemMap, nil
+	}
+
+	var items []*feed.Item
+	err := db.FindAllContext(ctx, &items, clause.Where("id"), clause.In(ids...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert items: %w", err)
+	}
+
+	for _, item := range items {
+		itemMap[item.ID] = item
+	}
+
+	return itemMap, nil
+}
+
+// Alerts returns the most recent alerts, newest first, with their items,
+// optionally only those of the given watchlist
+// If limit is 0, a default limit is applied
+func Alerts(ctx context.Context, db db.DB, watchlist string, limit uint) ([]*AlertItem, error) {
+	if limit == 0 {
+		limit = defaultAlertsLimit
+	}
+
+	var clauses []*clause.Clause
+	if watchlist != "" {
+		clauses = append(clauses, clause.Where("watchlist = ?", watchlist))
+	}
+	clauses = append(clauses, clause.OrderBy("id desc"), clause.Limit(limit))
+
+	var alerts []*feed.Alert
+	err := db.FindAllContext(ctx, &alerts, clauses...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", err)
+	}
+
+	var ids []interface{}
+	for _, alert := range alerts {
+		ids = append(ids, alert.ItemID)
+	}
+
+	itemMap, err := itemsByID(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Alerts whose items no longer exist are omitted
+	var alertItems []*AlertItem
+	for _, alert := range alerts {
+		item, exists := itemMap[alert.ItemID]
+		if !exists {
+			continue
+		}
+
+		alertItems = append(alertItems, &AlertItem{Alert: alert, Item: item})
+	}
+
+	return alertItems, nil
+}
+
+// Send the alerts which haven't been sent yet to the notifier
+// Alerts which fail to send are retried the next time, so a notifier may
+// receive an alert again if another notifier failed to send it
+func notifyAlerts(ctx context.Context, db db.DB, notifier notify.Notifier) error {
+	var alerts []*feed.Alert
+	err := db.FindAllContext(ctx, &alerts, clause.Where("notified = ?", false), clause.OrderBy("id asc"))
+	if err != nil {
+		return fmt.Errorf("failed to get new alerts: %w", err)
+	}
+
+	var ids []interface{}
+	for _, alert := range alerts {
+		ids = append(ids, alert.ItemID)
+	}
+
+	itemMap, err := itemsByID(ctx, db, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		item, exists := itemMap[alert.ItemID]
+		if exists {
+			err = notifier.Notify(alert, item)
+			if err != nil {
+				log.Error().Err(err).Msgf("Failed to send %s", alert)
+				continue
+			}
+		}
+
+		alert.Notified = true
+		err = db.SaveContext(ctx, alert)
+		if err != nil {
+			return fmt.Errorf("failed to save alert: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// NotifyAlerts periodically sends new alerts to the notifier
+func NotifyAlerts(ctx context.Context, db db.DB, notifier notify.Notifier) error {
+	ticker := time.NewTicker(alertsNotifyPeriod)
+	defer ticker.Stop()
+
+	for {
+		err := notifyAlerts(ctx, db, notifier)
+		if err != nil {
+			return fmt.Errorf("failed to notify alerts: %w", err)
+		}
+
+		select {
+		case <-ticker.C:
+			break
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"gonews/config"
+	"gonews/feed"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchlistMatchReturnsMatchingTerm(t *testing.T) {
+	watchlists, err := compileWatchlists([]*config.WatchlistConfig{
+		{Name: "products", Terms: []string{"GoNews", "gnctl"}, Regexes: []string{`CVE-\d{4}-\d+`}},
+	})
+	assert.NoError(t, err)
+	w := watchlists[0]
+
+	tests := []struct {
+		item *feed.Item
+		term string
+	}{
+		{&feed.Item{Title: "gonews 2.0 released"}, "GoNews"},
+		{&feed.Item{Title: "Release", Description: "<p>Use <code>gnctl</code> to migrate</p>"}, "gnctl"},
+		{&feed.Item{Title: "Advisory", Content: "<p>Fixed in CVE-2026-1234</p>"}, `CVE-\d{4}-\d+`},
+		{&feed.Item{Title: "Unrelated", Link: "https://example.com/gonews"}, ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.term, w.match(watchlistText(test.item)), test.item.Title)
+	}
+}
+
+func TestWatchlistMatchIgnoresMarkup(t *testing.T) {
+	watchlists, err := compileWatchlists([]*config.WatchlistConfig{
+		{Name: "products", Terms: []string{"a&b"}},
+	})
+	assert.NoError(t, err)
+
+	assert.Empty(t, watchlists[0].match(watchlistText(&feed.Item{Description: `<img alt="a&amp;b">`})))
+	assert.Equal(t, "a&b", watchlists[0].match(watchlistText(&feed.Item{Description: `<p>a&amp;b</p>`})))
+}
@@ -0,0 +1,95 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gonews/db"
+	"gonews/db/orm/query"
+	"gonews/db/orm/query/clause"
+	"gonews/feed"
+	"gonews/parser"
+	"time"
+)
+
+// FeedHealth pairs a feed with its most recent fetch status
+type FeedHealth struct {
+	Feed   *feed.Feed   `json:"feed"`
+	Status *feed.Status `json:"status"`
+}
+
+// Get the stored status of each feed, keyed by feed ID
+func feedStatuses(ctx context.Context, db db.DB) (map[uint]*feed.Status, error) {
+	var statuses []*feed.Status
+	err := db.AllContext(ctx, &statuses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed statuses: %w", err)
+	}
+
+	statusMap := make(map[uint]*feed.Status)
+	for _, s := range statuses {
+		statusMap[s.FeedID] = s
+	}
+
+	return statusMap, nil
+}
+
+// Return the HTTP status from the outcome of a fetch, or zero if the request
+// didn't get a response
+func httpStatus(resp *parser.Response, err error) int {
+	if resp != nil {
+		return resp.StatusCode
+	}
+
+	var httpErr parser.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode
+	}
+
+	return 0
+}
+
+// Record the outcome of a fetch in the feed's status
+func updateStatus(s *feed.Status, res *FeedResult, httpStatus int) {
+	now := time.Now()
+
+	s.LastHTTPStatus = httpStatus
+	if res.Err != nil {
+		s.LastErrorAt = now
+		s.LastError = res.Err.Error()
+		s.ConsecutiveFailures++
+	} else {
+		s.LastSuccessAt = now
+		s.ConsecutiveFailures = 0
+	}
+}
+
+// UnhealthyFeeds returns the feeds whose most recent fetch failed, along with
+// their statuses
+// Statuses of feeds which no longer exist are skipped
+func UnhealthyFeeds(ctx context.Context, db db.DB) ([]*FeedHealth, error) {
+	var statuses []*feed.Status
+	err := db.FindAllContext(ctx, &statuses, clause.Where("consecutive_failures > 0"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed statuses: %w", err)
+	}
+
+	var healths []*FeedHealth
+	for _, s := range statuses {
+		var f feed.Feed
+		err = db.FindContext(ctx, &f, clause.Where("id = ?", s.FeedID))
+		if errors.Is(err, query.ErrModelNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get matching feed: %w", err)
+		}
+
+		healths = append(healths, &FeedHealth{
+			Feed:   &f,
+			Status: s,
+		})
+	}
+
+	return healths, nil
+}
@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"gonews/config"
+	"gonews/db"
+	"gonews/feed"
+	"gonews/parser"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Period between checking for icons to refresh
+	iconsCheckPeriod = time.Hour
+
+	// Icons are refreshed once they're older than this, whether or not
+	// they were found
+	iconMaxAge = 7 * 24 * time.Hour
+)
+
+// IconPath returns the path of the feed's icon in the icons directory
+func IconPath(iconsDir string, feedID uint) string {
+	return filepath.Join(iconsDir, strconv.FormatUint(uint64(feedID), 10))
+}
+
+// Write the icon to its path, replacing the previous icon atomically, so that
+// it's never served partially written
+func writeIcon(iconsDir string, feedID uint, data []byte) error {
+	tmp, err := ioutil.TempFile(iconsDir, ".icon")
+	if err != nil {
+		return fmt.Errorf("failed to create icon file: %w", err)
+	}
+
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write icon file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), IconPath(iconsDir, feedID))
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to move icon file: %w", err)
+	}
+
+	return nil
+}
+
+// Fetch the icons of the feeds whose icons are missing or old
+// Feeds which haven't been fetched yet are skipped, since their images &
+// websites aren't known until then; if an icon can't be fetched, the previous
+// icon, if any, is kept until the next attempt
+func refreshIcons(ctx context.Context, db db.DB, p parser.Parser, iconsDir string) error {
+	var feeds []*feed.Feed
+	err := db.AllContext(ctx, &feeds)
+	if err != nil {
+		return fmt.Errorf("failed to get feeds: %w", err)
+	}
+
+	var icons []*feed.Icon
+	err = db.AllContext(ctx, &icons)
+	if err != nil {
+		return fmt.Errorf("failed to get icons: %w", err)
+	}
+
+	iconMap := make(map[uint]*feed.Icon)
+	for _, icon := range icons {
+		iconMap[icon.FeedID] = icon
+	}
+
+	for _, f := range feeds {
+		if f.NextFetchAt.IsZero() {
+			continue
+		}
+
+		icon, exists := iconMap[f.ID]
+		if exists && time.Since(icon.FetchedAt) < iconMaxAge {
+			continue
+		} else if !exists {
+			icon = &feed.Icon{FeedID: f.ID}
+		}
+
+		fetched, err := p.FetchIcon(ctx, f)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to fetch icon of %s", f.URL)
+		} else {
+			err = writeIcon(iconsDir, f.ID, fetched.Data)
+			if err != nil {
+				return err
+			}
+
+			icon.URL = fetched.URL
+			icon.ContentType = fetched.ContentType
+		}
+
+		icon.FetchedAt = time.Now()
+		err = db.SaveContext(ctx, icon)
+		if err != nil {
+			return fmt.Errorf("failed to save icon: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RefreshIcons periodically fetches the icons of the feeds into the icons
+// directory, refreshing each about once a week
+func RefreshIcons(ctx context.Context, cfg *config.Config, db db.DB, iconsDir string) error {
+	p, err := parser.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create feed parser: %w", err)
+	}
+
+	err = os.MkdirAll(iconsDir, 0750)
+	if err != nil {
+		return fmt.Errorf("failed to create icons directory: %w", err)
+	}
+
+	ticker := time.NewTicker(iconsCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		err = refreshIcons(ctx, db, p, iconsDir)
+		if err != nil {
+			return fmt.Errorf("failed to refresh icons: %w", err)
+		}
+
+		select {
+		case <-ticker.C:
+			break
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"gonews/config"
+	"gonews/db"
+	"gonews/db/orm/query/clause"
+	"gonews/feed"
+	"gonews/parser"
+	"html"
+	"net/url"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Maximum number of items whose attachments are loaded per query, to stay
+// under SQLite's limit on query parameters
+const attachmentsBatchSize = 500
+
+// Replace the item's content with the article extracted from its link
+// The feed's content is kept if the article can't be extracted, so that a
+// failure doesn't prevent the item from being saved
+// Relative links in the article are resolved against the page it's from
+func extractFullText(ctx context.Context, p parser.Parser, item *feed.Item) {
+	// Stored links are escaped; see feed.Item.FromGofeedItem
+	link, err := url.Parse(html.UnescapeString(item.Link))
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to parse article link: %s", item.Link)
+		return
+	}
+
+	article, err := p.ParseArticle(ctx, link.String())
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to extract article: %s", item.Link)
+		return
+	}
+
+	item.Content = feed.Sanitize(article, link)
+}
+
+// Extract the full text of the items which will be inserted, before they're
+// saved; see extractFullText
+// Items which are already in the feed, were purged, or are dropped by the
+// feed's rules are skipped, so that their articles aren't downloaded for
+// nothing
+func extractNewItemsFullText(ctx context.Context, cfg *config.Config, db db.Tx, p parser.Parser, f *feed.Feed, items []*feed.Item) error {
+	rules, err := compileRules(cfg.FeedRules(f.URL))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range limitItems(f, items) {
+		existingItem, err := findExistingItem(ctx, db, f, item)
+		if err != nil {
+			return err
+		} else if existingItem != nil {
+			continue
+		}
+
+		purged, err := isPurged(ctx, db, f, item)
+		if err != nil {
+			return err
+		} else if purged {
+			continue
+		}
+
+		if applyRules(rules, item).Action == config.RuleDrop {
+			continue
+		}
+
+		extractFullText(ctx, p, item)
+	}
+
+	return nil
+}
+
+// Save the categories, enclosures & extra authors of a newly inserted item
+func saveItemAttachments(ctx context.Context, db db.Tx, item *feed.Item) error {
+	for _, c := range item.Categories {
+		c.ItemID = item.ID
+
+		err := db.SaveContext(ctx, c)
+		if err != nil {
+			return fmt.Errorf("failed to save item category: %w", err)
+		}
+	}
+
+	for _, e := range item.Enclosures {
+		e.ItemID = item.ID
+
+		err := db.SaveContext(ctx, e)
+		if err != nil {
+			return fmt.Errorf("failed to save enclosure: %w", err)
+		}
+	}
+
+	for _, a := range item.Authors {
+		a.ItemID = item.ID
+
+		err := db.SaveContext(ctx, a)
+		if err != nil {
+			return fmt.Errorf("failed to save item author: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// LoadItemAttachments sets the categories, enclosures & extra authors of the
+// given items
+func LoadItemAttachments(ctx context.Context, db db.DB, items []*feed.Item) error {
+	itemMap := make(map[uint]*feed.Item)
+	for _, item := range items {
+		itemMap[item.ID] = item
+	}
+
+	for start := 0; start < len(items); start += attachmentsBatchSize {
+		end := start + attachmentsBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		var ids []interface{}
+		for _, item := range items[start:end] {
+			ids = append(ids, item.ID)
+		}
+
+		var categories []*feed.ItemCategory
+		err := db.FindAllContext(ctx, &categories, clause.Where("item_id"), clause.In(ids...))
+		if err != nil {
+			return fmt.Errorf("failed to get item categories: %w", err)
+		}
+
+		for _, c := range categories {
+			item := itemMap[c.ItemID]
+			item.Categories = append(item.Categories, c)
+		}
+
+		var enclosures []*feed.Enclosure
+		err = db.FindAllContext(ctx, &enclosures, clause.Where("item_id"), clause.In(ids...))
+		if err != nil {
+			return fmt.Errorf("failed to get enclosures: %w", err)
+		}
+
+		for _, e := range enclosures {
+			item := itemMap[e.ItemID]
+			item.Enclosures = append(item.Enclosures, e)
+		}
+
+		var authors []*feed.ItemAuthor
+		err = db.FindAllContext(ctx, &authors, clause.Where("item_id"), clause.In(ids...))
+		if err != nil {
+			return fmt.Errorf("failed to get item authors: %w", err)
+		}
+
+		for _, a := range authors {
+			item := itemMap[a.ItemID]
+			item.Authors = append(item.Authors, a)
+		}
+	}
+
+	return nil
+}
+
+// LoadItemFeedNames sets the feed names of the given items
+func LoadItemFeedNames(ctx context.Context, db db.DB, items []*feed.Item) error {
+	var feeds []*feed.Feed
+	err := db.AllContext(ctx, &feeds)
+	if err != nil {
+		return fmt.Errorf("failed to get feeds: %w", err)
+	}
+
+	names := make(map[uint]string)
+	for _, f := range feeds {
+		names[f.ID] = f.DisplayName()
+	}
+
+	for _, item := range items {
+		item.FeedName = names[item.FeedID]
+	}
+
+	return nil
+}
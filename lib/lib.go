@@ -2,6 +2,7 @@ package lib
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"gonews/config"
@@ -11,26 +12,30 @@ import (
 	"gonews/feed"
 	"gonews/parser"
 	"gonews/timestamp"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
-// Insert any nonexistent feeds & tags from the config into the database
-func InsertMissingFeeds(cfg *config.Config, db db.DB) error {
+// Insert any nonexistent feeds & tags from the config into the database, and
+// update existing feeds to match the config
+func InsertMissingFeeds(ctx context.Context, cfg *config.Config, db db.DB) error {
 	for _, cfgFeed := range cfg.Feeds {
-		f := &feed.Feed{
-			URL:        cfgFeed.URL,
-			FetchLimit: cfgFeed.FetchLimit,
-		}
-
-		var existingFeed feed.Feed
-		err := db.Find(&existingFeed, clause.Where("url = ?", f.URL))
+		var f feed.Feed
+		err := db.FindContext(ctx, &f, clause.Where("url = ?", cfgFeed.URL))
 		if err != nil && !errors.Is(err, query.ErrModelNotFound) {
 			return fmt.Errorf("failed to get matching feed: %w", err)
 		}
 
-		err = db.Save(f)
+		f.URL = cfgFeed.URL
+		f.FetchLimit = cfgFeed.FetchLimit
+		f.FetchPeriod = cfgFeed.FetchPeriod
+		f.FullText = cfgFeed.FullText
+		f.Name = cfgFeed.Name
+
+		err = db.SaveContext(ctx, &f)
 		if err != nil {
 			return fmt.Errorf("failed to save feed: %w", err)
 		}
@@ -44,12 +49,14 @@ func InsertMissingFeeds(cfg *config.Config, db db.DB) error {
 			}
 
 			var existingTag feed.Tag
-			err = db.Find(&existingTag, clause.Where("name = ?", t.Name))
+			err = db.FindContext(ctx, &existingTag, clause.Where("name = ? and feed_id = ?", t.Name, t.FeedID))
 			if err != nil && !errors.Is(err, query.ErrModelNotFound) {
 				return fmt.Errorf("failed to get matching tag: %w", err)
+			} else if err == nil {
+				continue
 			}
 
-			err = db.Save(t)
+			err = db.SaveContext(ctx, t)
 			if err != nil {
 				return fmt.Errorf("failed to save tag: %w", err)
 			}
@@ -61,118 +68,605 @@ func InsertMissingFeeds(cfg *config.Config, db db.DB) error {
 	return nil
 }
 
-func fetchFeeds(db db.DB, p parser.Parser) error {
-	var feeds []*feed.Feed
-	err := db.All(&feeds)
-	if err != nil {
-		return fmt.Errorf("failed to get feeds: %w", err)
+const (
+	defaultFetchWorkers        = 4
+	defaultFetchWorkersPerHost = 1
+)
+
+// ErrFeedNotFound is returned when refreshing a feed which doesn't exist
+var ErrFeedNotFound = errors.New("feed not found")
+
+// Held while items are written, so that fetches, pushes & purges don't
+// interleave their checks for existing & purged items with each other's writes
+// It's only held around the DB writes, not while feeds & articles are
+// downloaded, so that a slow fetch doesn't hold up the others
+var fetchMutex sync.Mutex
+
+// FeedResult contains the outcome of fetching a single feed
+// Dropped counts the new items which were dropped by the feed's filter rules
+type FeedResult struct {
+	FeedID      uint
+	URL         string
+	NotModified bool
+	Inserted    uint
+	Updated     uint
+	Skipped     uint
+	Dropped     uint
+	Err         error
+}
+
+func (r FeedResult) String() string {
+	return fmt.Sprintf(
+		"FeedResult{URL: %s, NotModified: %t, Inserted: %d, Updated: %d, Skipped: %d, Dropped: %d, Err: %v}",
+		r.URL,
+		r.NotModified,
+		r.Inserted,
+		r.Updated,
+		r.Skipped,
+		r.Dropped,
+		r.Err)
+}
+
+// MarshalJSON encodes the result with its error as a string, since errors
+// don't encode to JSON
+func (r FeedResult) MarshalJSON() ([]byte, error) {
+	var errText string
+	if r.Err != nil {
+		errText = r.Err.Error()
+	}
+
+	return json.Marshal(&struct {
+		FeedID      uint   `json:"feed_id"`
+		URL         string `json:"url"`
+		NotModified bool   `json:"not_modified"`
+		Inserted    uint   `json:"inserted"`
+		Updated     uint   `json:"updated"`
+		Skipped     uint   `json:"skipped"`
+		Dropped     uint   `json:"dropped"`
+		Err         string `json:"error,omitempty"`
+	}{
+		FeedID:      r.FeedID,
+		URL:         r.URL,
+		NotModified: r.NotModified,
+		Inserted:    r.Inserted,
+		Updated:     r.Updated,
+		Skipped:     r.Skipped,
+		Dropped:     r.Dropped,
+		Err:         errText,
+	})
+}
+
+// FetchSummary contains the outcome of a single pass over the feeds
+type FetchSummary struct {
+	Results     []*FeedResult
+	NextFetchAt time.Time
+}
+
+// Inserted returns the number of items inserted across all feeds
+func (s FetchSummary) Inserted() uint {
+	var n uint
+	for _, r := range s.Results {
+		n += r.Inserted
+	}
+
+	return n
+}
+
+// Updated returns the number of existing items whose content changed across
+// all feeds
+func (s FetchSummary) Updated() uint {
+	var n uint
+	for _, r := range s.Results {
+		n += r.Updated
 	}
 
+	return n
+}
+
+// Skipped returns the number of existing items skipped across all feeds
+func (s FetchSummary) Skipped() uint {
+	var n uint
+	for _, r := range s.Results {
+		n += r.Skipped
+	}
+
+	return n
+}
+
+// Dropped returns the number of new items dropped by filter rules across all
+// feeds
+func (s FetchSummary) Dropped() uint {
+	var n uint
+	for _, r := range s.Results {
+		n += r.Dropped
+	}
+
+	return n
+}
+
+// Failed returns the results of the feeds which couldn't be fetched
+func (s FetchSummary) Failed() []*FeedResult {
+	var failed []*FeedResult
+	for _, r := range s.Results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+
+	return failed
+}
+
+// MarshalJSON encodes the summary with its totals across all feeds
+func (s FetchSummary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Inserted    uint          `json:"inserted"`
+		Updated     uint          `json:"updated"`
+		Skipped     uint          `json:"skipped"`
+		Dropped     uint          `json:"dropped"`
+		Failed      int           `json:"failed"`
+		Results     []*FeedResult `json:"results"`
+		NextFetchAt time.Time     `json:"next_fetch_at"`
+	}{
+		Inserted:    s.Inserted(),
+		Updated:     s.Updated(),
+		Skipped:     s.Skipped(),
+		Dropped:     s.Dropped(),
+		Failed:      len(s.Failed()),
+		Results:     s.Results,
+		NextFetchAt: s.NextFetchAt,
+	})
+}
+
+func (s FetchSummary) String() string {
+	return fmt.Sprintf(
+		"FetchSummary{Feeds: %d, Inserted: %d, Updated: %d, Skipped: %d, Dropped: %d, Failed: %d, NextFetchAt: %s}",
+		len(s.Results),
+		s.Inserted(),
+		s.Updated(),
+		s.Skipped(),
+		s.Dropped(),
+		len(s.Failed()),
+		s.NextFetchAt)
+}
+
+type parsedFeed struct {
+	idx  int
+	resp *parser.Response
+	err  error
+}
+
+func feedHost(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil || u.Host == "" {
+		return feedURL
+	}
+
+	return u.Host
+}
+
+// Parse the given feeds using a bounded pool of workers, with at most
+// perHost concurrent requests to any single host
+// Fetches in progress are stopped when the context is done
+func parseFeeds(ctx context.Context, p parser.Parser, feeds []*feed.Feed, workers, perHost uint) <-chan *parsedFeed {
+	if workers == 0 {
+		workers = defaultFetchWorkers
+	}
+
+	if perHost == 0 {
+		perHost = defaultFetchWorkersPerHost
+	}
+
+	hostSems := make(map[string]chan struct{})
 	for _, f := range feeds {
-		items, err := p.ParseURL(f.URL)
-		if err != nil {
-			return fmt.Errorf("failed to parse feed: %w", err)
+		host := feedHost(f.URL)
+		if _, exists := hostSems[host]; !exists {
+			hostSems[host] = make(chan struct{}, perHost)
 		}
+	}
 
-		if len(items) == 0 {
-			log.Warn().Msgf("%s feed is empty", f.URL)
-			continue
+	jobs := make(chan int)
+	results := make(chan *parsedFeed)
+
+	var wg sync.WaitGroup
+	for i := uint(0); i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for idx := range jobs {
+				sem := hostSems[feedHost(feeds[idx].URL)]
+
+				sem <- struct{}{}
+				resp, err := p.ParseFeed(ctx, feeds[idx])
+				<-sem
+
+				results <- &parsedFeed{
+					idx:  idx,
+					resp: resp,
+					err:  err,
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for idx := range feeds {
+			jobs <- idx
 		}
+		close(jobs)
+
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// Return the items of the feed which are saved: the first FetchLimit items, if
+// it's set, or else all of them
+func limitItems(f *feed.Feed, items []*feed.Item) []*feed.Item {
+	if f.FetchLimit != 0 && uint(len(items)) > f.FetchLimit {
+		return items[:f.FetchLimit]
+	}
+
+	return items
+}
 
-		if f.FetchLimit != 0 && uint(len(items)) > f.FetchLimit {
-			items = items[:f.FetchLimit]
+// Get the clauses which match copies of the given item in the feed, checking
+// by GUID, then by link, then by content hash
+// Items with different GUIDs are distinct even if their links or content
+// match, ex. comment pages which share a link, so an item with a GUID is only
+// compared by link & hash against items without one
+func itemMatchClauses(f *feed.Feed, item *feed.Item) []*clause.Clause {
+	// Items without links, ex. from Maildir feeds, aren't matched by link
+	var where []*clause.Clause
+	if item.GUID != "" {
+		where = append(where, clause.Where("feed_id = ? and guid = ?", f.ID, item.GUID))
+		if item.Link != "" {
+			where = append(where, clause.Where("feed_id = ? and guid = '' and link = ?", f.ID, item.Link))
+		}
+		if item.Hash != "" {
+			where = append(where, clause.Where("feed_id = ? and guid = '' and hash = ?", f.ID, item.Hash))
+		}
+	} else {
+		if item.Link != "" {
+			where = append(where, clause.Where("feed_id = ? and link = ?", f.ID, item.Link))
+		}
+		if item.Hash != "" {
+			where = append(where, clause.Where("feed_id = ? and hash = ?", f.ID, item.Hash))
 		}
+	}
 
-		for _, item := range items {
-			// Don't insert item if there's an existing item with
-			// the same author, title & link
-			var existingItem feed.Item
-			err = db.Find(&existingItem, clause.Where("link = ?", item.Link))
-			if err != nil && !errors.Is(err, query.ErrModelNotFound) {
-				return fmt.Errorf("failed to get matching item: %w", err)
-			} else if err == nil {
-				log.Info().Msgf("skipping: %s", item)
-				continue
-			}
+	return where
+}
 
-			item.FeedID = f.ID
+// Find the item in the feed which the given item is another copy of; returns
+// nil if there's none
+func findExistingItem(ctx context.Context, db db.Tx, f *feed.Feed, item *feed.Item) (*feed.Item, error) {
+	for _, w := range itemMatchClauses(f, item) {
+		var existingItem feed.Item
+		err := db.FindContext(ctx, &existingItem, w)
+		if err == nil {
+			return &existingItem, nil
+		} else if !errors.Is(err, query.ErrModelNotFound) {
+			return nil, fmt.Errorf("failed to get matching item: %w", err)
+		}
+	}
 
-			err = db.Save(item)
+	return nil, nil
+}
+
+// Insert the given items into the feed, revising any which already exist but
+// whose content changed, and skipping the rest; the counts are added to the
+// given result
+// Items which were purged by the feed's retention policy aren't inserted again
+// New items are filtered by the feed's rules before they're inserted, and
+// matched against its watchlists after
+func saveItems(ctx context.Context, cfg *config.Config, db db.Tx, p parser.Parser, f *feed.Feed, items []*feed.Item, res *FeedResult) error {
+	if len(items) == 0 {
+		log.Warn().Msgf("%s feed is empty", f.URL)
+		return nil
+	}
+
+	rules, err := compileRules(cfg.FeedRules(f.URL))
+	if err != nil {
+		return err
+	}
+
+	watchlists, err := compileWatchlists(cfg.FeedWatchlists(f.URL))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range limitItems(f, items) {
+		existingItem, err := findExistingItem(ctx, db, f, item)
+		if err != nil {
+			return err
+		} else if existingItem != nil && itemChanged(existingItem, item) {
+			err = reviseItem(ctx, cfg, db, existingItem, item)
 			if err != nil {
-				return fmt.Errorf("failed to save item: %w", err)
+				return err
 			}
 
-			log.Debug().Msgf("inserted: %s", item)
+			log.Info().Msgf("updated: %s", existingItem)
+			res.Updated++
+			continue
+		} else if existingItem != nil {
+			log.Info().Msgf("skipping: %s", item)
+			res.Skipped++
+			continue
+		}
+
+		purged, err := isPurged(ctx, db, f, item)
+		if err != nil {
+			return err
+		} else if purged {
+			log.Debug().Msgf("skipping purged: %s", item)
+			res.Skipped++
+			continue
+		}
+
+		item.FeedID = f.ID
+
+		match := applyRules(rules, item)
+		if match.Action == config.RuleDrop {
+			log.Debug().Msgf("dropped: %s", item)
+			res.Dropped++
+			continue
+		} else if match.Action == config.RuleHide {
+			item.Hide = true
+		}
+
+		// Without a parser, the articles were already extracted
+		if f.FullText && p != nil {
+			extractFullText(ctx, p, item)
+		}
+
+		err = db.SaveContext(ctx, item)
+		if err != nil {
+			return fmt.Errorf("failed to save item: %w", err)
+		}
+
+		err = saveItemAttachments(ctx, db, item)
+		if err != nil {
+			return err
 		}
+
+		err = saveAlerts(ctx, db, watchlists, item)
+		if err != nil {
+			return err
+		}
+
+		log.Debug().Msgf("inserted: %s", item)
+		res.Inserted++
 	}
 
 	return nil
 }
 
-// Periodically parse feeds from the DB and insert any nonexistent items,
-// subject to the fetch period from the given config
-func WatchFeeds(ctx context.Context, cfg *config.Config, dbCfg *config.DBConfig) error {
-	db, err := db.New(dbCfg)
+// Insert the given items into the feed in a single transaction, so that either
+// all of them are saved or, if any fails, none are
+// Full-text articles are fetched before the transaction begins, so that the
+// DB & fetchMutex aren't locked while they're downloaded
+func saveFeedItems(ctx context.Context, cfg *config.Config, adb db.DB, p parser.Parser, f *feed.Feed, items []*feed.Item, res *FeedResult) error {
+	if f.FullText {
+		err := extractNewItemsFullText(ctx, cfg, adb, p, f, items)
+		if err != nil {
+			return err
+		}
+	}
+
+	fetchMutex.Lock()
+	defer fetchMutex.Unlock()
+
+	// The counts are only added to the result once the transaction commits
+	saved := &FeedResult{}
+	err := adb.TransactionContext(ctx, func(tx db.Tx) error {
+		return saveItems(ctx, cfg, tx, nil, f, items, saved)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create db client: %w", err)
+		return err
 	}
 
-	defer db.Close()
+	res.Inserted += saved.Inserted
+	res.Updated += saved.Updated
+	res.Skipped += saved.Skipped
+	res.Dropped += saved.Dropped
+
+	return nil
+}
+
+// Schedule the feed's next fetch and save any changes to it
+func updateFeed(ctx context.Context, cfg *config.Config, db db.DB, f *feed.Feed, failures uint) error {
+	schedErr := scheduleFeed(ctx, cfg, db, f, failures)
+	if schedErr != nil {
+		// Still push the next fetch back, so that a failing feed isn't
+		// retried immediately
+		f.NextFetchAt = time.Now().Add(withJitter(backoff(globalFetchPeriod(cfg), failures)))
+	}
 
-	parser, err := parser.New()
+	err := db.SaveContext(ctx, f)
 	if err != nil {
-		return fmt.Errorf("failed to create feed parser: %w", err)
+		return fmt.Errorf("failed to save feed: %w", err)
 	}
 
-	fetchPeriod := cfg.FetchPeriod
-	var lastFetched timestamp.Timestamp
-	err = db.Find(&lastFetched, clause.Where("name = 'feeds_fetched_at'"))
-	if errors.Is(err, query.ErrModelNotFound) {
-		lastFetched = timestamp.Timestamp{Name: "feeds_fetched_at"}
+	if schedErr != nil {
+		return fmt.Errorf("failed to schedule feed: %w", schedErr)
+	}
 
-	} else if err != nil {
-		return fmt.Errorf("failed to get matching timestamp: %w", err)
+	return nil
+}
+
+// Fetch every feed which is due concurrently and insert any nonexistent items
+// Failures are recorded per feed in the returned summary, so that one
+// unreachable feed doesn't prevent the others from being fetched; an error is
+// only returned if the feeds themselves can't be read
+func fetchFeeds(ctx context.Context, cfg *config.Config, db db.DB, p parser.Parser) (*FetchSummary, error) {
+	now := time.Now()
+	return fetchSelectedFeeds(ctx, cfg, db, p, func(f *feed.Feed) bool {
+		return !f.NextFetchAt.After(now)
+	})
+}
+
+// RefreshFeeds immediately fetches the feed with the given ID, or every feed if
+// the ID is 0, whether or not they're due, and inserts any nonexistent items
+// It may run alongside the periodic fetches, since items are written one feed
+// at a time; ErrFeedNotFound is returned if there's no feed with the ID
+func RefreshFeeds(ctx context.Context, cfg *config.Config, db db.DB, p parser.Parser, feedID uint) (*FetchSummary, error) {
+	summary, err := fetchSelectedFeeds(ctx, cfg, db, p, func(f *feed.Feed) bool {
+		return feedID == 0 || f.ID == feedID
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	for {
-		wait := fetchPeriod - time.Since(lastFetched.T)
-		if wait > 0 {
-			timer := time.NewTimer(wait)
-			select {
-			case <-timer.C:
-				break
-			case <-ctx.Done():
-				return nil
+	if feedID != 0 && len(summary.Results) == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrFeedNotFound, feedID)
+	}
+
+	return summary, nil
+}
+
+// Fetch the selected feeds concurrently and insert any nonexistent items
+func fetchSelectedFeeds(ctx context.Context, cfg *config.Config, db db.DB, p parser.Parser, selected func(*feed.Feed) bool) (*FetchSummary, error) {
+	var feeds []*feed.Feed
+	err := db.AllContext(ctx, &feeds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feeds: %w", err)
+	}
+
+	statuses, err := feedStatuses(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []*feed.Feed
+	for _, f := range feeds {
+		if selected(f) {
+			due = append(due, f)
+		}
+	}
+
+	summary := &FetchSummary{
+		Results: make([]*FeedResult, len(due)),
+	}
+
+	// Items are saved as each feed is parsed, rather than from the workers,
+	// so that only one goroutine writes to the DB at a time
+	for parsed := range parseFeeds(ctx, p, due, cfg.FetchWorkers, cfg.FetchWorkersPerHost) {
+		f := due[parsed.idx]
+		res := &FeedResult{
+			FeedID: f.ID,
+			URL:    f.URL,
+		}
+
+		if parsed.err != nil {
+			res.Err = fmt.Errorf("failed to parse feed: %w", parsed.err)
+		} else if parsed.resp.NotModified {
+			log.Debug().Msgf("%s feed not modified", f.URL)
+			res.NotModified = true
+		} else {
+			// Items beyond the feed's limit aren't saved, so they
+			// aren't committed either
+			items := limitItems(f, parsed.resp.Items)
+			res.Err = saveFeedItems(ctx, cfg, db, p, f, items, res)
+
+			// Only store the validators once the items are saved, so
+			// that a failed save is retried with an unconditional
+			// request
+			if res.Err == nil {
+				f.ETag = parsed.resp.ETag
+				f.LastModified = parsed.resp.LastModified
+			}
+
+			if parsed.resp.Metadata != nil {
+				f.SetMetadata(parsed.resp.Metadata)
+			}
+
+			if res.Err == nil && parsed.resp.Commit != nil {
+				err := parsed.resp.Commit(items)
+				if err != nil {
+					res.Err = fmt.Errorf("failed to commit feed: %w", err)
+				}
+			}
+
+			if cfg.WebSubCallbackURL != "" && parsed.resp.Links.Hub != "" {
+				err := ensureSubscription(ctx, db, f, parsed.resp.Links)
+				if err != nil {
+					// Polling continues to work without a
+					// subscription
+					log.Error().Err(err).Msgf("Failed to subscribe to %s", f.URL)
+				}
 			}
 		}
 
-		err := fetchFeeds(db, parser)
-		if err != nil {
-			return fmt.Errorf("failed to fetch feeds: %w", err)
+		status, exists := statuses[f.ID]
+		if !exists {
+			status = &feed.Status{FeedID: f.ID}
 		}
 
-		lastFetched.T = time.Now()
-		err = db.Save(&lastFetched)
-		if err != nil {
-			return fmt.Errorf("failed to update timestamp: %w", err)
+		updateStatus(status, res, httpStatus(parsed.resp, parsed.err))
+
+		// Failing feeds are backed off according to the failure count
+		err := updateFeed(ctx, cfg, db, f, status.ConsecutiveFailures)
+		if err != nil && res.Err == nil {
+			res.Err = err
 		}
+
+		err = db.SaveContext(ctx, status)
+		if err != nil && res.Err == nil {
+			res.Err = fmt.Errorf("failed to save feed status: %w", err)
+		}
+
+		if res.Err != nil {
+			log.Error().Err(res.Err).Msgf("Failed to fetch %s", f.URL)
+		}
+
+		summary.Results[parsed.idx] = res
 	}
 
-	return nil
+	summary.NextFetchAt = nextFetchAt(cfg, feeds)
+
+	return summary, nil
 }
 
-// Periodically hide items older than the configured duration
-func AutoDismissItems(ctx context.Context, cfg *config.Config, dbCfg *config.DBConfig) error {
-	db, err := db.New(dbCfg)
+// Periodically parse feeds from the DB and insert any nonexistent items,
+// waking whenever the next feed is due
+func WatchFeeds(ctx context.Context, cfg *config.Config, db db.DB) error {
+	parser, err := parser.New(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create db client: %w", err)
+		return fmt.Errorf("failed to create feed parser: %w", err)
 	}
 
-	defer db.Close()
+	for {
+		summary, err := fetchFeeds(ctx, cfg, db, parser)
+		if err != nil {
+			return fmt.Errorf("failed to fetch feeds: %w", err)
+		}
 
+		log.Info().Msgf("fetched feeds: %s", summary)
+
+		wait := time.Until(summary.NextFetchAt)
+		if wait < minFetchWait {
+			wait = minFetchWait
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			break
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+	}
+}
+
+// Periodically hide items older than the configured duration
+func AutoDismissItems(ctx context.Context, cfg *config.Config, adb db.DB) error {
 	autoDismissPeriod := cfg.AutoDismissPeriod
 	var lastAutoDismissed timestamp.Timestamp
-	err = db.Find(&lastAutoDismissed, clause.Where("name = 'auto_dismissed_at'"))
+	err := adb.FindContext(ctx, &lastAutoDismissed, clause.Where("name = 'auto_dismissed_at'"))
 	if errors.Is(err, query.ErrModelNotFound) {
 		lastAutoDismissed = timestamp.Timestamp{Name: "auto_dismissed_at"}
 	} else if err != nil {
@@ -191,36 +685,53 @@ func AutoDismissItems(ctx context.Context, cfg *config.Config, dbCfg *config.DBC
 			}
 		}
 
-		for _, feedCfg := range cfg.Feeds {
-			var f feed.Feed
-			err = db.Find(&f, clause.Where("url = ?", feedCfg.URL))
+		// The items are hidden & the timestamp updated together, so that
+		// an interrupted pass is repeated in full
+		err = adb.TransactionContext(ctx, func(tx db.Tx) error {
+			err := autoDismissItems(ctx, cfg, tx)
 			if err != nil {
-				return fmt.Errorf("failed to get matching feed: %w", err)
+				return err
 			}
 
-			var items []*feed.Item
-			err = db.FindAll(&items, clause.Where("feed_id = ?", f.ID))
+			lastAutoDismissed.T = time.Now()
+			err = tx.SaveContext(ctx, &lastAutoDismissed)
 			if err != nil {
-				return fmt.Errorf("failed to get items from feed: %w", err)
+				return fmt.Errorf("failed to update timestamp: %w", err)
 			}
 
-			for _, item := range items {
-				if time.Now().Before(item.CreatedAt.Add(feedCfg.AutoDismissAfter)) {
-					continue
-				}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
 
-				item.Hide = true
-				err := db.Save(item)
-				if err != nil {
-					return fmt.Errorf("failed to save item: %w", err)
-				}
-			}
+// Hide the items of each feed which are older than its AutoDismissAfter
+func autoDismissItems(ctx context.Context, cfg *config.Config, db db.Tx) error {
+	for _, feedCfg := range cfg.Feeds {
+		var f feed.Feed
+		err := db.FindContext(ctx, &f, clause.Where("url = ?", feedCfg.URL))
+		if err != nil {
+			return fmt.Errorf("failed to get matching feed: %w", err)
 		}
 
-		lastAutoDismissed.T = time.Now()
-		err = db.Save(&lastAutoDismissed)
+		var items []*feed.Item
+		err = db.FindAllContext(ctx, &items, clause.Where("feed_id = ?", f.ID))
 		if err != nil {
-			return fmt.Errorf("failed to update timestamp: %w", err)
+			return fmt.Errorf("failed to get items from feed: %w", err)
+		}
+
+		for _, item := range items {
+			if time.Now().Before(item.CreatedAt.Add(feedCfg.AutoDismissAfter)) {
+				continue
+			}
+
+			item.Hide = true
+			err := db.SaveContext(ctx, item)
+			if err != nil {
+				return fmt.Errorf("failed to save item: %w", err)
+			}
 		}
 	}
 
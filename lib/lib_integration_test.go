@@ -2,14 +2,23 @@ package lib
 
 import (
 	"context"
+	"errors"
 	"gonews/config"
+	"gonews/db"
 	"gonews/db/orm/query/clause"
 	"gonews/feed"
+	"gonews/mock_parser"
+	"gonews/parser"
 	"gonews/rss"
 	"gonews/test"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -45,7 +54,8 @@ func testConfig(t *testing.T) *config.Config {
 
 var expectedFeeds []*feed.Feed = []*feed.Feed{
 	{
-		URL: "http://localhost:8081",
+		URL:   "http://localhost:8081",
+		Title: "FeedForAll Sample Feed",
 	},
 }
 
@@ -93,10 +103,10 @@ func expectedItems() []*feed.Item {
 }
 
 func TestWatchFeeds(t *testing.T) {
-	dbCfg, db := test.InitDB(t, migrationsDir)
+	_, db := test.InitDB(t, migrationsDir)
 	testCfg := testConfig(t)
 
-	err := InsertMissingFeeds(testCfg, db)
+	err := InsertMissingFeeds(context.Background(), testCfg, db)
 	assert.NoError(t, err)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -110,7 +120,7 @@ func TestWatchFeeds(t *testing.T) {
 	}()
 
 	go func() {
-		err := WatchFeeds(ctx, testCfg, dbCfg)
+		err := WatchFeeds(ctx, testCfg, db)
 		if ctx.Err() != context.Canceled {
 			assert.NoError(t, err)
 		}
@@ -151,10 +161,10 @@ func TestWatchFeeds(t *testing.T) {
 }
 
 func TestAutoDismissItems(t *testing.T) {
-	dbCfg, db := test.InitDB(t, migrationsDir)
+	_, db := test.InitDB(t, migrationsDir)
 	testCfg := testConfig(t)
 
-	err := InsertMissingFeeds(testCfg, db)
+	err := InsertMissingFeeds(context.Background(), testCfg, db)
 	assert.NoError(t, err)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -168,14 +178,14 @@ func TestAutoDismissItems(t *testing.T) {
 	}()
 
 	go func() {
-		err := WatchFeeds(ctx, testCfg, dbCfg)
+		err := WatchFeeds(ctx, testCfg, db)
 		if ctx.Err() != context.Canceled {
 			assert.NoError(t, err)
 		}
 	}()
 
 	go func() {
-		err := AutoDismissItems(ctx, testCfg, dbCfg)
+		err := AutoDismissItems(ctx, testCfg, db)
 		if ctx.Err() != context.Canceled {
 			assert.NoError(t, err)
 		}
@@ -199,7 +209,7 @@ func TestAutoDismissItems(t *testing.T) {
 }
 
 func TestAutoDismissItemsIgnoresItemsYoungerThanAutoDismissAfter(t *testing.T) {
-	dbCfg, db := test.InitDB(t, migrationsDir)
+	_, db := test.InitDB(t, migrationsDir)
 	testCfg := testConfig(t)
 
 	autoDismissAfter, err := time.ParseDuration("1h")
@@ -207,7 +217,7 @@ func TestAutoDismissItemsIgnoresItemsYoungerThanAutoDismissAfter(t *testing.T) {
 
 	testCfg.Feeds[0].AutoDismissAfter = autoDismissAfter
 
-	err = InsertMissingFeeds(testCfg, db)
+	err = InsertMissingFeeds(context.Background(), testCfg, db)
 	assert.NoError(t, err)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -221,14 +231,14 @@ func TestAutoDismissItemsIgnoresItemsYoungerThanAutoDismissAfter(t *testing.T) {
 	}()
 
 	go func() {
-		err := WatchFeeds(ctx, testCfg, dbCfg)
+		err := WatchFeeds(ctx, testCfg, db)
 		if ctx.Err() != context.Canceled {
 			assert.NoError(t, err)
 		}
 	}()
 
 	go func() {
-		err := AutoDismissItems(ctx, testCfg, dbCfg)
+		err := AutoDismissItems(ctx, testCfg, db)
 		if ctx.Err() != context.Canceled {
 			assert.NoError(t, err)
 		}
@@ -250,3 +260,610 @@ func TestAutoDismissItemsIgnoresItemsYoungerThanAutoDismissAfter(t *testing.T) {
 		assert.False(t, item.Hide)
 	}
 }
+
+func TestUnhealthyFeeds(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+	testCfg.Feeds = append(testCfg.Feeds, &config.FeedConfig{
+		URL: "http://localhost:1",
+	})
+
+	err := InsertMissingFeeds(context.Background(), testCfg, db)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		err := rss.Serve(ctx, "test/sample.xml", 8081)
+		if ctx.Err() != context.Canceled {
+			assert.NoError(t, err)
+		}
+	}()
+
+	go func() {
+		err := WatchFeeds(ctx, testCfg, db)
+		if ctx.Err() != context.Canceled {
+			assert.NoError(t, err)
+		}
+	}()
+
+	d, err := time.ParseDuration("3s")
+	assert.NoError(t, err)
+
+	time.Sleep(d)
+
+	healths, err := UnhealthyFeeds(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Len(t, healths, 1)
+	assert.Equal(t, "http://localhost:1", healths[0].Feed.URL)
+	assert.NotZero(t, healths[0].Status.ConsecutiveFailures)
+	assert.NotEmpty(t, healths[0].Status.LastError)
+}
+
+func TestUnhealthyFeedsDoesNotWaitForTransactions(t *testing.T) {
+	_, adb := test.InitDB(t, migrationsDir)
+
+	err := InsertMissingFeeds(context.Background(), testConfig(t), adb)
+	assert.NoError(t, err)
+
+	locked := make(chan struct{})
+	unlock := make(chan struct{})
+	saved := make(chan error)
+	go func() {
+		saved <- adb.Transaction(func(tx db.Tx) error {
+			err := tx.Save(&feed.Feed{URL: "http://localhost:1"})
+			close(locked)
+			<-unlock
+			return err
+		})
+	}()
+
+	<-locked
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Reads don't take the write lock, so they don't wait for the
+	// transaction to finish
+	healths, err := UnhealthyFeeds(ctx, adb)
+	assert.NoError(t, err)
+	assert.Empty(t, healths)
+
+	close(unlock)
+	assert.NoError(t, <-saved)
+}
+
+func TestSaveItemsDeduplicatesItems(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+
+	f := &feed.Feed{URL: "http://localhost:8081"}
+	assert.NoError(t, db.Save(f))
+
+	otherFeed := &feed.Feed{URL: "http://localhost:8082"}
+	assert.NoError(t, db.Save(otherFeed))
+
+	err := saveItems(context.Background(), testCfg, db, nil, f, []*feed.Item{
+		{Title: "Story", Link: "https://example.com/story", GUID: "1", Hash: feed.ContentHash("Story", "")},
+		{Title: "Post", Link: "https://example.com/post", Hash: feed.ContentHash("Post", "")},
+	}, &FeedResult{})
+	assert.NoError(t, err)
+
+	res := &FeedResult{}
+	err = saveItems(context.Background(), testCfg, db, nil, f, []*feed.Item{
+		// Same GUID, updated link
+		{Title: "Story", Link: "https://example.com/story-1", GUID: "1", Hash: feed.ContentHash("Story", "")},
+		// Same link, different GUID
+		{Title: "Comments", Link: "https://example.com/story", GUID: "2", Hash: feed.ContentHash("Comments", "")},
+		// Same content, different link
+		{Title: "Post", Link: "https://example.com/post-1", Hash: feed.ContentHash("Post", "")},
+		// Same link, no GUID, updated content
+		{Title: "Post (updated)", Link: "https://example.com/post", Hash: feed.ContentHash("Post (updated)", "")},
+	}, res)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), res.Inserted)
+	assert.Equal(t, uint(1), res.Updated)
+	assert.Equal(t, uint(2), res.Skipped)
+
+	// Items are only deduplicated within a feed
+	res = &FeedResult{}
+	err = saveItems(context.Background(), testCfg, db, nil, otherFeed, []*feed.Item{
+		{Title: "Story", Link: "https://example.com/story", GUID: "1", Hash: feed.ContentHash("Story", "")},
+	}, res)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), res.Inserted)
+	assert.Equal(t, uint(0), res.Skipped)
+}
+
+func TestSaveItemsRevisesUpdatedItems(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+	testCfg.UnhideUpdatedItems = true
+
+	f := &feed.Feed{URL: "http://localhost:8081"}
+	assert.NoError(t, db.Save(f))
+
+	item := &feed.Item{
+		Title:       "Advisory",
+		Description: "Affects version 1",
+		Link:        "https://example.com/advisory",
+		Content:     "Affects version 1 only",
+		GUID:        "advisory",
+		Hash:        feed.ContentHash("Advisory", "Affects version 1"),
+	}
+	err := saveItems(context.Background(), testCfg, db, nil, f, []*feed.Item{item}, &FeedResult{})
+	assert.NoError(t, err)
+
+	item.Hide = true
+	assert.NoError(t, db.Save(item))
+
+	res := &FeedResult{}
+	err = saveItems(context.Background(), testCfg, db, nil, f, []*feed.Item{
+		{
+			Title:       "Advisory",
+			Description: "Affects versions 1 and 2",
+			Link:        "https://example.com/advisory-update",
+			GUID:        "advisory",
+			Hash:        feed.ContentHash("Advisory", "Affects versions 1 and 2"),
+		},
+	}, res)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), res.Updated)
+
+	var updatedItem feed.Item
+	assert.NoError(t, db.Find(&updatedItem, clause.Where("id = ?", item.ID)))
+	assert.Equal(t, "Affects versions 1 and 2", updatedItem.Description)
+	assert.False(t, updatedItem.Hide)
+	assert.False(t, updatedItem.RevisedAt.IsZero())
+
+	diffs, err := ItemRevisions(context.Background(), db, item.ID)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 1)
+	assert.Equal(t, "Affects version 1", diffs[0].Revision.Description)
+	assert.Equal(t, "https://example.com/advisory", diffs[0].Revision.Link)
+	assert.Equal(t, "Affects version 1 only", diffs[0].Revision.Content)
+	assert.Equal(t, []*DiffOp{{Op: "=", Text: "Advisory"}}, diffs[0].Title)
+	assert.Equal(t, []*DiffOp{
+		{Op: "=", Text: "Affects"},
+		{Op: "-", Text: "version"},
+		{Op: "+", Text: "versions"},
+		{Op: "=", Text: "1"},
+		{Op: "+", Text: "and 2"},
+	}, diffs[0].Description)
+}
+
+func TestSaveItemsSavesAttachments(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+
+	f := &feed.Feed{URL: "http://localhost:8081"}
+	assert.NoError(t, db.Save(f))
+
+	err := saveItems(context.Background(), testCfg, db, nil, f, []*feed.Item{
+		{
+			Title:      "Episode",
+			Link:       "https://example.com/episode",
+			Content:    "Show notes",
+			Categories: []*feed.ItemCategory{{Name: "podcast"}, {Name: "security"}},
+			Enclosures: []*feed.Enclosure{{URL: "https://example.com/episode.mp3", Length: 1024, Type: "audio/mpeg"}},
+			Authors:    []*feed.ItemAuthor{{Name: "Guest"}},
+		},
+		{
+			Title: "Post",
+			Link:  "https://example.com/post",
+		},
+	}, &FeedResult{})
+	assert.NoError(t, err)
+
+	var items []*feed.Item
+	assert.NoError(t, db.All(&items))
+	assert.Len(t, items, 2)
+	assert.Nil(t, items[0].Categories)
+
+	err = LoadItemAttachments(context.Background(), db, items)
+	assert.NoError(t, err)
+	assert.Equal(t, "Show notes", items[0].Content)
+	assert.Len(t, items[0].Categories, 2)
+	assert.Equal(t, "podcast", items[0].Categories[0].Name)
+	assert.Len(t, items[0].Enclosures, 1)
+	assert.Equal(t, int64(1024), items[0].Enclosures[0].Length)
+	assert.Len(t, items[0].Authors, 1)
+	assert.Equal(t, "Guest", items[0].Authors[0].Name)
+	assert.Empty(t, items[1].Categories)
+	assert.Empty(t, items[1].Enclosures)
+	assert.Empty(t, items[1].Authors)
+}
+
+func TestSaveItemsExtractsFullText(t *testing.T) {
+	article := `<html><body><nav><a href="/">Home</a></nav><div class="entry">` +
+		`<p>The full article, which the feed only publishes a teaser of, is long enough to be recognized as the body of the page.</p>` +
+		`<p>It continues with a second paragraph, so that the container of both paragraphs is chosen, rather than either one of them on its own, and the page is long enough.</p>` +
+		`</div></body></html>`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(article))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+
+	p, err := parser.New(nil)
+	assert.NoError(t, err)
+
+	f := &feed.Feed{URL: "http://localhost:8081", FullText: true}
+	assert.NoError(t, db.Save(f))
+
+	err = saveItems(context.Background(), testCfg, db, p, f, []*feed.Item{
+		{
+			Title:       "Article",
+			Description: "A teaser",
+			Link:        server.URL + "/article",
+		},
+		{
+			Title:       "Missing",
+			Description: "Another teaser",
+			Content:     "The feed's content",
+			Link:        server.URL + "/missing",
+		},
+	}, &FeedResult{})
+	assert.NoError(t, err)
+
+	var items []*feed.Item
+	assert.NoError(t, db.All(&items))
+	assert.Len(t, items, 2)
+	assert.Contains(t, items[0].Content, "<p>The full article")
+	assert.NotContains(t, items[0].Content, "Home")
+	assert.Equal(t, "A teaser", items[0].Description)
+	assert.Equal(t, "The feed's content", items[1].Content)
+}
+
+func TestSaveFeedItemsExtractsFullTextOfNewItems(t *testing.T) {
+	var requested []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+
+	p, err := parser.New(nil)
+	assert.NoError(t, err)
+
+	f := &feed.Feed{URL: "http://localhost:8081", FullText: true}
+	assert.NoError(t, db.Save(f))
+
+	existing := &feed.Item{FeedID: f.ID, Title: "Existing", Link: server.URL + "/existing"}
+	assert.NoError(t, db.Save(existing))
+
+	res := &FeedResult{}
+	err = saveFeedItems(context.Background(), testCfg, db, p, f, []*feed.Item{
+		{Title: "Existing", Link: server.URL + "/existing"},
+		{Title: "New", Link: server.URL + "/new"},
+	}, res)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), res.Inserted)
+	assert.Equal(t, uint(1), res.Skipped)
+	assert.Equal(t, []string{"/new"}, requested)
+
+	var items []*feed.Item
+	assert.NoError(t, db.All(&items))
+	assert.Len(t, items, 2)
+}
+
+func TestSaveFeedItemsDoesNotExtractPurgedOrDroppedItems(t *testing.T) {
+	var requested []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+	testCfg.Feeds = []*config.FeedConfig{
+		{
+			URL: "http://localhost:8081",
+			Rules: []*config.RuleConfig{
+				{Action: config.RuleDrop, Fields: []string{"title"}, Keywords: []string{"sponsored"}},
+			},
+		},
+	}
+
+	p, err := parser.New(nil)
+	assert.NoError(t, err)
+
+	f := &feed.Feed{URL: "http://localhost:8081", FullText: true, PurgedAt: time.Now()}
+	assert.NoError(t, db.Save(f))
+	assert.NoError(t, db.Save(&feed.Tombstone{FeedID: f.ID, Link: server.URL + "/purged"}))
+
+	res := &FeedResult{}
+	err = saveFeedItems(context.Background(), testCfg, db, p, f, []*feed.Item{
+		{Title: "Purged", Link: server.URL + "/purged"},
+		{Title: "Sponsored post", Link: server.URL + "/sponsored"},
+		{Title: "New", Link: server.URL + "/new"},
+	}, res)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), res.Inserted)
+	assert.Equal(t, uint(1), res.Skipped)
+	assert.Equal(t, uint(1), res.Dropped)
+	assert.Equal(t, []string{"/new"}, requested)
+}
+
+func TestSaveItemsAppliesRules(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+	testCfg.Feeds = []*config.FeedConfig{
+		{
+			URL:  "http://localhost:8081",
+			Tags: []string{"Tech"},
+			Rules: []*config.RuleConfig{
+				{Action: config.RuleDrop, Fields: []string{"title"}, Regex: `^Show HN`},
+			},
+		},
+	}
+	testCfg.Tags = map[string]*config.TagConfig{
+		"tech": {
+			Rules: []*config.RuleConfig{
+				{Action: config.RuleHide, Keywords: []string{"crypto"}},
+			},
+		},
+	}
+
+	f := &feed.Feed{URL: "http://localhost:8081"}
+	assert.NoError(t, db.Save(f))
+
+	res := &FeedResult{}
+	err := saveItems(context.Background(), testCfg, db, nil, f, []*feed.Item{
+		{Title: "Show HN: A crypto library", Link: "https://example.com/1"},
+		{Title: "Crypto exchange hacked", Link: "https://example.com/2"},
+		{Title: "New TLS attack", Link: "https://example.com/3"},
+	}, res)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), res.Inserted)
+	assert.Equal(t, uint(1), res.Dropped)
+
+	var items []*feed.Item
+	assert.NoError(t, db.All(&items))
+	assert.Len(t, items, 2)
+	assert.Equal(t, "Crypto exchange hacked", items[0].Title)
+	assert.True(t, items[0].Hide)
+	assert.Equal(t, "New TLS attack", items[1].Title)
+	assert.False(t, items[1].Hide)
+
+	// Rules are tested against the stored items without changing them
+	testCfg.Tags["tech"].Rules[0].Keywords = []string{"tls"}
+	matches, err := TestRules(context.Background(), testCfg, db, f.URL)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "New TLS attack", matches[0].Item.Title)
+	assert.Equal(t, config.RuleHide, matches[0].Action)
+	assert.False(t, matches[0].Item.Hide)
+}
+
+type recordingNotifier struct {
+	alerts []*feed.Alert
+	err    error
+}
+
+func (n *recordingNotifier) Notify(alert *feed.Alert, item *feed.Item) error {
+	if n.err != nil {
+		return n.err
+	}
+
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func TestSaveItemsRaisesAlerts(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+	testCfg.Watchlists = []*config.WatchlistConfig{
+		{Name: "products", Terms: []string{"gonews"}},
+		{Name: "advisories", Regexes: []string{`CVE-\d+-\d+`}, Tags: []string{"TAG1"}},
+		{Name: "other", Terms: []string{"gonews"}, Tags: []string{"other"}},
+	}
+
+	f := &feed.Feed{URL: "http://localhost:8081"}
+	assert.NoError(t, db.Save(f))
+
+	res := &FeedResult{}
+	err := saveItems(context.Background(), testCfg, db, nil, f, []*feed.Item{
+		{Title: "GoNews fixes CVE-2026-1234", Link: "https://example.com/1"},
+		{Title: "New TLS attack", Link: "https://example.com/2"},
+	}, res)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), res.Inserted)
+
+	alerts, err := Alerts(context.Background(), db, "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, alerts, 2)
+	assert.Equal(t, "advisories", alerts[0].Alert.Watchlist)
+	assert.Equal(t, `CVE-\d+-\d+`, alerts[0].Alert.Term)
+	assert.Equal(t, "products", alerts[1].Alert.Watchlist)
+	assert.Equal(t, "gonews", alerts[1].Alert.Term)
+	assert.Equal(t, "GoNews fixes CVE-2026-1234", alerts[1].Item.Title)
+	assert.Equal(t, f.ID, alerts[1].Alert.FeedID)
+
+	alerts, err = Alerts(context.Background(), db, "products", 0)
+	assert.NoError(t, err)
+	assert.Len(t, alerts, 1)
+
+	// Failed alerts are retried, and sent alerts aren't sent again
+	notifier := &recordingNotifier{err: errors.New("unavailable")}
+	assert.NoError(t, notifyAlerts(context.Background(), db, notifier))
+	notifier.err = nil
+	assert.NoError(t, notifyAlerts(context.Background(), db, notifier))
+	assert.Len(t, notifier.alerts, 2)
+	assert.NoError(t, notifyAlerts(context.Background(), db, notifier))
+	assert.Len(t, notifier.alerts, 2)
+
+	alerts, err = Alerts(context.Background(), db, "", 0)
+	assert.NoError(t, err)
+	assert.True(t, alerts[0].Alert.Notified)
+}
+
+func TestLoadItemFeedNames(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+
+	named := &feed.Feed{URL: "https://example.com/named", Name: "Named", Title: "Title"}
+	titled := &feed.Feed{URL: "https://example.com/titled", Title: "News &amp; Views"}
+	assert.NoError(t, db.Save(named))
+	assert.NoError(t, db.Save(titled))
+
+	items := []*feed.Item{
+		{Title: "First", FeedID: named.ID},
+		{Title: "Second", FeedID: titled.ID},
+	}
+	for _, item := range items {
+		assert.NoError(t, db.Save(item))
+	}
+
+	var stored []*feed.Item
+	assert.NoError(t, db.All(&stored))
+	assert.NoError(t, LoadItemFeedNames(context.Background(), db, stored))
+	assert.Equal(t, "Named", stored[0].FeedName)
+	assert.Equal(t, "News &amp; Views", stored[1].FeedName)
+}
+
+func TestRefreshIcons(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+	ctrl := gomock.NewController(t)
+
+	iconsDir, err := ioutil.TempDir("", "icons")
+	assert.NoError(t, err)
+	defer os.RemoveAll(iconsDir)
+
+	fetched := &feed.Feed{URL: "https://example.com/feed", NextFetchAt: time.Now()}
+	unfetched := &feed.Feed{URL: "https://example.com/new"}
+	assert.NoError(t, db.Save(fetched))
+	assert.NoError(t, db.Save(unfetched))
+
+	p := mock_parser.NewMockParser(ctrl)
+	p.EXPECT().FetchIcon(gomock.Any(), gomock.Any()).Return(&parser.Icon{
+		URL:         "https://example.com/favicon.ico",
+		ContentType: "image/x-icon",
+		Data:        []byte("icon"),
+	}, nil)
+
+	assert.NoError(t, refreshIcons(context.Background(), db, p, iconsDir))
+
+	var icon feed.Icon
+	assert.NoError(t, db.Find(&icon, clause.Where("feed_id = ?", fetched.ID)))
+	assert.Equal(t, "image/x-icon", icon.ContentType)
+	data, err := ioutil.ReadFile(IconPath(iconsDir, fetched.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, "icon", string(data))
+
+	// Recent icons aren't fetched again
+	assert.NoError(t, refreshIcons(context.Background(), db, p, iconsDir))
+
+	// Old icons are kept if they can't be fetched again
+	icon.FetchedAt = time.Now().Add(-iconMaxAge)
+	assert.NoError(t, db.Save(&icon))
+	p.EXPECT().FetchIcon(gomock.Any(), gomock.Any()).Return(nil, parser.ErrIconNotFound)
+
+	assert.NoError(t, refreshIcons(context.Background(), db, p, iconsDir))
+
+	assert.NoError(t, db.Find(&icon, clause.Where("feed_id = ?", fetched.ID)))
+	assert.Equal(t, "image/x-icon", icon.ContentType)
+	assert.True(t, time.Since(icon.FetchedAt) < time.Minute)
+	data, err = ioutil.ReadFile(IconPath(iconsDir, fetched.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, "icon", string(data))
+}
+
+func TestPurgeItems(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+	testCfg.Retention = &config.RetentionConfig{HiddenAfter: time.Nanosecond}
+	testCfg.Feeds[0].Retention = &config.RetentionConfig{MaxItems: 2}
+
+	f := &feed.Feed{URL: "http://localhost:8081"}
+	assert.NoError(t, db.Save(f))
+
+	items := []*feed.Item{
+		{
+			Title:      "Oldest",
+			GUID:       "oldest",
+			Link:       "https://example.com/oldest",
+			Categories: []*feed.ItemCategory{{Name: "security"}},
+		},
+		{Title: "Old", Link: "https://example.com/old"},
+		{Title: "Hidden", Link: "https://example.com/hidden"},
+		{Title: "Newest", Link: "https://example.com/newest"},
+	}
+	assert.NoError(t, saveItems(context.Background(), testCfg, db, nil, f, items, &FeedResult{}))
+
+	var hidden feed.Item
+	assert.NoError(t, db.Find(&hidden, clause.Where("title = ?", "Hidden")))
+	hidden.Hide = true
+	assert.NoError(t, db.Save(&hidden))
+
+	purged, err := purgeItems(context.Background(), testCfg, db)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), purged)
+
+	var remaining []*feed.Item
+	assert.NoError(t, db.All(&remaining))
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "Newest", remaining[0].Title)
+
+	var categories []*feed.ItemCategory
+	assert.NoError(t, db.All(&categories))
+	assert.Empty(t, categories)
+
+	var tombstones []*feed.Tombstone
+	assert.NoError(t, db.All(&tombstones))
+	assert.Len(t, tombstones, 3)
+
+	// Purged items aren't inserted again while the feed still contains them
+	assert.NoError(t, db.Find(f, clause.Where("id = ?", f.ID)))
+	assert.False(t, f.PurgedAt.IsZero())
+
+	res := &FeedResult{}
+	items = append(items, &feed.Item{Title: "New", Link: "https://example.com/new"})
+	assert.NoError(t, saveItems(context.Background(), testCfg, db, nil, f, items, res))
+	assert.Equal(t, uint(1), res.Inserted)
+	assert.Equal(t, uint(4), res.Skipped)
+}
+
+func TestPurgeItemsKeepsItemsWithoutRetention(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+	testCfg := testConfig(t)
+
+	f := &feed.Feed{URL: "http://localhost:8081"}
+	assert.NoError(t, db.Save(f))
+	assert.NoError(t, db.Save(&feed.Item{FeedID: f.ID, Title: "Hidden", Hide: true}))
+
+	purged, err := purgeItems(context.Background(), testCfg, db)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), purged)
+
+	var items []*feed.Item
+	assert.NoError(t, db.All(&items))
+	assert.Len(t, items, 1)
+}
+
+func TestUnhealthyFeedsSkipsStatusesOfMissingFeeds(t *testing.T) {
+	_, db := test.InitDB(t, migrationsDir)
+
+	f := &feed.Feed{URL: "http://localhost:1"}
+	assert.NoError(t, db.Save(f))
+	assert.NoError(t, db.Save(&feed.Status{FeedID: f.ID, ConsecutiveFailures: 1}))
+	assert.NoError(t, db.Save(&feed.Status{FeedID: f.ID + 1, ConsecutiveFailures: 1}))
+
+	healths, err := UnhealthyFeeds(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Len(t, healths, 1)
+	assert.Equal(t, f.ID, healths[0].Feed.ID)
+}
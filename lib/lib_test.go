@@ -1,15 +1,21 @@
 package lib
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"gonews/config"
+	"gonews/db"
 	"gonews/db/orm/query"
 	"gonews/db/orm/query/clause"
 	"gonews/feed"
 	"gonews/mock_db"
 	"gonews/mock_parser"
+	"gonews/parser"
 	"gonews/test"
 	"math/rand"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -24,9 +30,9 @@ func TestInsertMissingFeedsReturnsErrorWhenMatchingFeedFails(t *testing.T) {
 	mockErr := mockError()
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(mockErr)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
 
-	err := InsertMissingFeeds(mockCfg, db)
+	err := InsertMissingFeeds(context.Background(), mockCfg, db)
 	expectedErrMsg := fmt.Sprintf(
 		"failed to get matching feed: %v",
 		mockErr.Error())
@@ -40,10 +46,10 @@ func TestInsertMissingFeedsReturnsErrorWhenFeedSaveFails(t *testing.T) {
 	mockErr := mockError()
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(mockErr)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), gomock.Any()).Return(mockErr)
 
-	err := InsertMissingFeeds(mockCfg, db)
+	err := InsertMissingFeeds(context.Background(), mockCfg, db)
 	expectedErrMsg := fmt.Sprintf(
 		"failed to save feed: %v",
 		mockErr.Error())
@@ -57,11 +63,11 @@ func TestInsertMissingFeedsReturnsErrorWhenMatchingTagFails(t *testing.T) {
 	mockErr := mockError()
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(mockErr)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), gomock.Any()).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
 
-	err := InsertMissingFeeds(mockCfg, db)
+	err := InsertMissingFeeds(context.Background(), mockCfg, db)
 	expectedErrMsg := fmt.Sprintf(
 		"failed to get matching tag: %v",
 		mockErr.Error())
@@ -75,46 +81,89 @@ func TestInsertMissingFeedsReturnsErrorWhenTagSaveFails(t *testing.T) {
 	mockErr := mockError()
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(mockErr)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), gomock.Any()).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), gomock.Any()).Return(mockErr)
 
-	err := InsertMissingFeeds(mockCfg, db)
+	err := InsertMissingFeeds(context.Background(), mockCfg, db)
 	expectedErrMsg := fmt.Sprintf(
 		"failed to save tag: %v",
 		mockErr.Error())
 	assert.EqualError(t, err, expectedErrMsg)
 }
 
+func TestInsertMissingFeedsUpdatesExistingFeeds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockCfg.Feeds = mockCfg.Feeds[:1]
+	mockCfg.Feeds[0].Tags = nil
+	mockCfg.Feeds[0].FetchPeriod = time.Hour
+	mockCfg.Feeds[0].Name = "Test Feed"
+
+	existingFeed := &feed.Feed{
+		ID:  1,
+		URL: mockCfg.Feeds[0].URL,
+	}
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
+		f, ok := ptr.(*feed.Feed)
+		assert.True(t, ok)
+
+		*f = *existingFeed
+
+		return nil
+	})
+	db.EXPECT().SaveContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		f, ok := ptr.(*feed.Feed)
+		assert.True(t, ok)
+
+		assert.Equal(t, existingFeed.ID, f.ID)
+		assert.Equal(t, mockCfg.Feeds[0].FetchLimit, f.FetchLimit)
+		assert.Equal(t, mockCfg.Feeds[0].FetchPeriod, f.FetchPeriod)
+		assert.Equal(t, mockCfg.Feeds[0].Name, f.Name)
+
+		return nil
+	})
+
+	err := InsertMissingFeeds(context.Background(), mockCfg, db)
+	assert.NoError(t, err)
+}
+
 func TestFetchFeedsReturnsErrorWhenFeedsReturnsError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
+	mockCfg := mockConfig(t)
 	mockErr := mockError()
 
 	parser := mock_parser.NewMockParser(ctrl)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().All(gomock.Any()).Return(mockErr)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).Return(mockErr)
 
-	err := fetchFeeds(db, parser)
+	_, err := fetchFeeds(context.Background(), mockCfg, db, parser)
 	expectedErrMsg := fmt.Sprintf(
 		"failed to get feeds: %v",
 		mockErr.Error())
 	assert.EqualError(t, err, expectedErrMsg)
 }
 
-func TestFetchFeedsReturnsErrorWhenFeedParseFails(t *testing.T) {
+func TestFetchFeedsRecordsErrorWhenFeedParseFails(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
+	mockCfg := mockConfig(t)
 	mockFeeds := mockFeeds()
+	mockFeedItems := test.MockItems()
 	mockErr := mockError()
 
 	parser := mock_parser.NewMockParser(ctrl)
-	parser.EXPECT().ParseURL(mockFeeds[0].URL).Return(nil, mockErr)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[0]).Return(nil, mockErr)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[1]).Return(mockResponse(mockFeedItems), nil)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().All(gomock.Any()).DoAndReturn(func(ptr interface{}) error {
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
 		feeds, ok := ptr.(*[]*feed.Feed)
 		assert.True(t, ok)
 
@@ -122,83 +171,137 @@ func TestFetchFeedsReturnsErrorWhenFeedParseFails(t *testing.T) {
 
 		return nil
 	})
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	expectFeedsUpdated(db, 2)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
 
-	err := fetchFeeds(db, parser)
+	// The failing feed shouldn't prevent the other feed from being fetched
 	expectedErrMsg := fmt.Sprintf(
 		"failed to parse feed: %v",
 		mockErr.Error())
-	assert.EqualError(t, err, expectedErrMsg)
+	assert.EqualError(t, summary.Results[0].Err, expectedErrMsg)
+	assert.NoError(t, summary.Results[1].Err)
+	assert.Equal(t, uint(2), summary.Results[1].Inserted)
+	assert.Len(t, summary.Failed(), 1)
 }
 
-func TestFetchFeedsReturnsErrorWhenItemSaveFails(t *testing.T) {
+func TestFetchFeedsRecordsErrorWhenItemSaveFails(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
-	mockFeeds := mockFeeds()
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
 	mockFeedItems := test.MockItems()
 	mockErr := mockError()
 
 	parser := mock_parser.NewMockParser(ctrl)
-	parser.EXPECT().ParseURL(mockFeeds[0].URL).Return(mockFeedItems, nil)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockResponse(mockFeedItems), nil)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().All(gomock.Any()).DoAndReturn(func(ptr interface{}) error {
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
 		feeds, ok := ptr.(*[]*feed.Feed)
 		assert.True(t, ok)
 
-		*feeds = mockFeeds
+		*feeds = []*feed.Feed{mockFeed}
 
 		return nil
 	})
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(mockErr)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(mockErr)
+	expectFeedsUpdated(db, 1)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
 
-	err := fetchFeeds(db, parser)
 	expectedErrMsg := fmt.Sprintf(
 		"failed to save item: %v",
 		mockErr.Error())
-	assert.EqualError(t, err, expectedErrMsg)
+	assert.EqualError(t, summary.Results[0].Err, expectedErrMsg)
 }
 
-func TestFetchFeedsReturnsErrorWhenMatchingItemFails(t *testing.T) {
+func TestFetchFeedsDoesNotCountItemsWhenLaterItemSaveFails(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
-	mockFeeds := mockFeeds()
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
 	mockFeedItems := test.MockItems()
 	mockErr := mockError()
 
 	parser := mock_parser.NewMockParser(ctrl)
-	parser.EXPECT().ParseURL(mockFeeds[0].URL).Return(mockFeedItems, nil)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockResponse(mockFeedItems), nil)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().All(gomock.Any()).DoAndReturn(func(ptr interface{}) error {
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
 		feeds, ok := ptr.(*[]*feed.Feed)
 		assert.True(t, ok)
 
-		*feeds = mockFeeds
+		*feeds = []*feed.Feed{mockFeed}
+
+		return nil
+	})
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(mockErr)
+	expectFeedsUpdated(db, 1)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+
+	// The first item is rolled back along with the second
+	assert.Error(t, summary.Results[0].Err)
+	assert.Equal(t, uint(0), summary.Inserted())
+}
+
+func TestFetchFeedsRecordsErrorWhenMatchingItemFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
+	mockFeedItems := test.MockItems()
+	mockErr := mockError()
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockResponse(mockFeedItems), nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = []*feed.Feed{mockFeed}
 
 		return nil
 	})
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(mockErr)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+	expectFeedsUpdated(db, 1)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
 
-	err := fetchFeeds(db, parser)
 	expectedErrMsg := fmt.Sprintf(
 		"failed to get matching item: %v",
 		mockErr.Error())
-	assert.EqualError(t, err, expectedErrMsg)
+	assert.EqualError(t, summary.Results[0].Err, expectedErrMsg)
 }
 
 func TestFetchFeedsSavesItems(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
+	mockCfg := mockConfig(t)
 	mockFeeds := mockFeeds()
 	mockFeedItems := test.MockItems()
 
 	parser := mock_parser.NewMockParser(ctrl)
-	parser.EXPECT().ParseURL(mockFeeds[0].URL).Return(mockFeedItems, nil)
-	parser.EXPECT().ParseURL(mockFeeds[1].URL).Return(mockFeedItems, nil)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[0]).Return(mockResponse(mockFeedItems), nil)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[1]).Return(mockResponse(mockFeedItems), nil)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().All(gomock.Any()).DoAndReturn(func(ptr interface{}) error {
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
 		feeds, ok := ptr.(*[]*feed.Feed)
 		assert.True(t, ok)
 
@@ -206,33 +309,37 @@ func TestFetchFeedsSavesItems(t *testing.T) {
 
 		return nil
 	})
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
-
-	err := fetchFeeds(db, parser)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	expectFeedsUpdated(db, 2)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
 	assert.NoError(t, err)
+	assert.Equal(t, uint(4), summary.Inserted())
+	assert.Empty(t, summary.Failed())
 }
 
 func TestFetchFeedsSkipsMatchingItems(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
+	mockCfg := mockConfig(t)
 	mockFeeds := mockFeeds()
 	mockFeedItems := test.MockItems()
 	item1 := mockFeedItems[0]
 	item2 := mockFeedItems[1]
 
 	parser := mock_parser.NewMockParser(ctrl)
-	parser.EXPECT().ParseURL(mockFeeds[0].URL).Return(mockFeedItems, nil)
-	parser.EXPECT().ParseURL(mockFeeds[1].URL).Return(mockFeedItems, nil)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[0]).Return(mockResponse(mockFeedItems), nil)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[1]).Return(mockResponse(mockFeedItems), nil)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().All(gomock.Any()).DoAndReturn(func(ptr interface{}) error {
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
 		feeds, ok := ptr.(*[]*feed.Feed)
 		assert.True(t, ok)
 
@@ -240,7 +347,7 @@ func TestFetchFeedsSkipsMatchingItems(t *testing.T) {
 
 		return nil
 	})
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(ptr interface{}, clauses ...*clause.Clause) error {
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
 		item, ok := ptr.(*feed.Item)
 		assert.True(t, ok)
 
@@ -248,7 +355,7 @@ func TestFetchFeedsSkipsMatchingItems(t *testing.T) {
 
 		return nil
 	})
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(ptr interface{}, clauses ...*clause.Clause) error {
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
 		item, ok := ptr.(*feed.Item)
 		assert.True(t, ok)
 
@@ -256,28 +363,32 @@ func TestFetchFeedsSkipsMatchingItems(t *testing.T) {
 
 		return nil
 	})
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	expectFeedsUpdated(db, 2)
 
-	err := fetchFeeds(db, parser)
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
 	assert.NoError(t, err)
+	assert.Equal(t, uint(2), summary.Inserted())
+	assert.Equal(t, uint(2), summary.Skipped())
 }
 
 func TestFetchFeedsOmitsItemsAfterItemLimit(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
+	mockCfg := mockConfig(t)
 	mockFeed := randFeed()
 	mockFeed.FetchLimit = 1
 
 	mockFeedItems := test.MockItems()
 
 	parser := mock_parser.NewMockParser(ctrl)
-	parser.EXPECT().ParseURL(mockFeed.URL).Return(mockFeedItems, nil)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockResponse(mockFeedItems), nil)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().All(gomock.Any()).DoAndReturn(func(ptr interface{}) error {
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
 		feeds, ok := ptr.(*[]*feed.Feed)
 		assert.True(t, ok)
 
@@ -285,26 +396,28 @@ func TestFetchFeedsOmitsItemsAfterItemLimit(t *testing.T) {
 
 		return nil
 	})
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	expectFeedsUpdated(db, 1)
 
-	err := fetchFeeds(db, parser)
+	_, err := fetchFeeds(context.Background(), mockCfg, db, parser)
 	assert.NoError(t, err)
 }
 
 func TestFetchFeedsDoesNotOmitItemsIfSliceIsTooSmall(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
+	mockCfg := mockConfig(t)
 	mockFeed := randFeed()
 	mockFeed.FetchLimit = 3
 
 	mockFeedItems := test.MockItems()
 
 	parser := mock_parser.NewMockParser(ctrl)
-	parser.EXPECT().ParseURL(mockFeed.URL).Return(mockFeedItems, nil)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockResponse(mockFeedItems), nil)
 
 	db := mock_db.NewMockDB(ctrl)
-	db.EXPECT().All(gomock.Any()).DoAndReturn(func(ptr interface{}) error {
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
 		feeds, ok := ptr.(*[]*feed.Feed)
 		assert.True(t, ok)
 
@@ -312,15 +425,461 @@ func TestFetchFeedsDoesNotOmitItemsIfSliceIsTooSmall(t *testing.T) {
 
 		return nil
 	})
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
-	db.EXPECT().Find(gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
-	db.EXPECT().Save(gomock.Any()).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	expectFeedsUpdated(db, 1)
 
-	err := fetchFeeds(db, parser)
+	_, err := fetchFeeds(context.Background(), mockCfg, db, parser)
 	assert.NoError(t, err)
 }
 
+func TestFetchFeedsSkipsUnmodifiedFeeds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
+	mockFeed.ETag = `"abc"`
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(notModifiedResponse(mockFeed), nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = []*feed.Feed{mockFeed}
+
+		return nil
+	})
+	expectFeedsUpdated(db, 1)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+	assert.True(t, summary.Results[0].NotModified)
+	assert.Zero(t, summary.Inserted())
+}
+
+func TestFetchFeedsCommitsSavedItems(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
+
+	var committed bool
+	resp := mockResponse(test.MockItems())
+	resp.Commit = func([]*feed.Item) error {
+		committed = true
+		return nil
+	}
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(resp, nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = []*feed.Feed{mockFeed}
+
+		return nil
+	})
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	expectFeedsUpdated(db, 1)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+	assert.NoError(t, summary.Results[0].Err)
+	assert.True(t, committed)
+}
+
+func TestFetchFeedsOnlyCommitsItemsWithinFetchLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
+	mockFeed.FetchLimit = 1
+
+	var committed []*feed.Item
+	resp := mockResponse(test.MockItems())
+	resp.Commit = func(items []*feed.Item) error {
+		committed = items
+		return nil
+	}
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(resp, nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = []*feed.Feed{mockFeed}
+
+		return nil
+	})
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	expectFeedsUpdated(db, 1)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+	assert.NoError(t, summary.Results[0].Err)
+	assert.Equal(t, resp.Items[:1], committed)
+}
+
+func TestFetchFeedsDoesNotCommitWhenItemSaveFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
+
+	var committed bool
+	resp := mockResponse(test.MockItems())
+	resp.Commit = func([]*feed.Item) error {
+		committed = true
+		return nil
+	}
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(resp, nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = []*feed.Feed{mockFeed}
+
+		return nil
+	})
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(mockError())
+	expectFeedsUpdated(db, 1)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+	assert.Error(t, summary.Results[0].Err)
+	assert.False(t, committed)
+}
+
+func TestFetchFeedsSavesChangedValidators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
+	mockFeed.FetchLimit = 1
+
+	mockResp := mockResponse(test.MockItems())
+	mockResp.ETag = `"abc"`
+	mockResp.LastModified = "Tue, 19 Oct 2004 13:39:14 GMT"
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockResp, nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	expectTransactions(db)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = []*feed.Feed{mockFeed}
+
+		return nil
+	})
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+	db.EXPECT().AllContext(gomock.Any(), anyStatuses).Return(nil)
+	db.EXPECT().FindAllContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	db.EXPECT().SaveContext(gomock.Any(), anyStatus).Return(nil)
+	db.EXPECT().SaveContext(gomock.Any(), mockFeed).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		f, ok := ptr.(*feed.Feed)
+		assert.True(t, ok)
+
+		assert.Equal(t, mockResp.ETag, f.ETag)
+		assert.Equal(t, mockResp.LastModified, f.LastModified)
+
+		return nil
+	})
+
+	_, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+}
+
+func TestFetchFeedsSavesFeedMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
+
+	mockResp := mockResponse(nil)
+	mockResp.Metadata = &feed.Metadata{
+		Title:       "Test",
+		Link:        "https://example.com/",
+		Description: "Test posts",
+		Image:       "https://example.com/icon.png",
+		Language:    "en",
+	}
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockResp, nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	expectTransactions(db)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = []*feed.Feed{mockFeed}
+
+		return nil
+	})
+	db.EXPECT().AllContext(gomock.Any(), anyStatuses).Return(nil)
+	db.EXPECT().FindAllContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	db.EXPECT().SaveContext(gomock.Any(), anyStatus).Return(nil)
+	db.EXPECT().SaveContext(gomock.Any(), mockFeed).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		f, ok := ptr.(*feed.Feed)
+		assert.True(t, ok)
+
+		assert.Equal(t, "Test", f.Title)
+		assert.Equal(t, "https://example.com/", f.Link)
+		assert.Equal(t, "Test posts", f.Description)
+		assert.Equal(t, "https://example.com/icon.png", f.Image)
+		assert.Equal(t, "en", f.Language)
+
+		return nil
+	})
+
+	_, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+}
+
+func TestFetchFeedsSkipsFeedsWhichAreNotDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeeds := mockFeeds()
+	mockFeeds[1].NextFetchAt = time.Now().Add(time.Hour)
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[0]).Return(mockResponse(nil), nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = mockFeeds
+
+		return nil
+	})
+	expectFeedsUpdated(db, 1)
+
+	summary, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+	assert.Len(t, summary.Results, 1)
+	assert.Equal(t, mockFeeds[0].URL, summary.Results[0].URL)
+	assert.True(t, mockFeeds[0].NextFetchAt.After(time.Now()))
+	assert.False(t, summary.NextFetchAt.After(mockFeeds[0].NextFetchAt))
+}
+
+func TestRefreshFeedsFetchesFeedsWhichAreNotDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeeds := mockFeeds()
+	for _, f := range mockFeeds {
+		f.NextFetchAt = time.Now().Add(time.Hour)
+	}
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[0]).Return(mockResponse(nil), nil)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[1]).Return(notModifiedResponse(mockFeeds[1]), nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = mockFeeds
+
+		return nil
+	})
+	expectFeedsUpdated(db, 2)
+
+	summary, err := RefreshFeeds(context.Background(), mockCfg, db, parser, 0)
+	assert.NoError(t, err)
+	assert.Len(t, summary.Results, 2)
+	assert.True(t, summary.Results[1].NotModified)
+
+	text, err := json.Marshal(summary)
+	assert.NoError(t, err)
+	assert.Contains(t, string(text), `"inserted":0`)
+	assert.Contains(t, string(text), `"not_modified":true`)
+}
+
+func TestRefreshFeedsFetchesOnlyGivenFeed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeeds := mockFeeds()
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeeds[1]).Return(mockResponse(nil), nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = mockFeeds
+
+		return nil
+	})
+	expectFeedsUpdated(db, 1)
+
+	summary, err := RefreshFeeds(context.Background(), mockCfg, db, parser, mockFeeds[1].ID)
+	assert.NoError(t, err)
+	assert.Len(t, summary.Results, 1)
+	assert.Equal(t, mockFeeds[1].URL, summary.Results[0].URL)
+}
+
+func TestRefreshFeedsReturnsErrorWhenFeedNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeeds := mockFeeds()
+
+	parser := mock_parser.NewMockParser(ctrl)
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = mockFeeds
+
+		return nil
+	})
+	db.EXPECT().AllContext(gomock.Any(), anyStatuses).Return(nil)
+
+	_, err := RefreshFeeds(context.Background(), mockCfg, db, parser, mockFeeds[0].ID+mockFeeds[1].ID)
+	assert.True(t, errors.Is(err, ErrFeedNotFound))
+}
+
+func TestFetchFeedsRecordsFeedStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeeds := mockFeeds()
+	mockErr := parser.HTTPError{
+		StatusCode: 503,
+		Status:     "503 Service Unavailable",
+	}
+
+	existingStatus := &feed.Status{
+		FeedID:              mockFeeds[0].ID,
+		ConsecutiveFailures: 2,
+	}
+
+	p := mock_parser.NewMockParser(ctrl)
+	p.EXPECT().ParseFeed(gomock.Any(), mockFeeds[0]).Return(nil, mockErr)
+	p.EXPECT().ParseFeed(gomock.Any(), mockFeeds[1]).Return(mockResponse(nil), nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	expectTransactions(db)
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = mockFeeds
+
+		return nil
+	})
+	db.EXPECT().AllContext(gomock.Any(), anyStatuses).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		statuses, ok := ptr.(*[]*feed.Status)
+		assert.True(t, ok)
+
+		*statuses = []*feed.Status{existingStatus}
+
+		return nil
+	})
+	db.EXPECT().FindAllContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	db.EXPECT().SaveContext(gomock.Any(), anyFeed).Return(nil).Times(2)
+
+	var savedStatuses []*feed.Status
+	db.EXPECT().SaveContext(gomock.Any(), anyStatus).Times(2).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		savedStatuses = append(savedStatuses, ptr.(*feed.Status))
+		return nil
+	})
+
+	_, err := fetchFeeds(context.Background(), mockCfg, db, p)
+	assert.NoError(t, err)
+
+	assert.Len(t, savedStatuses, 2)
+	for _, s := range savedStatuses {
+		if s == existingStatus {
+			assert.Equal(t, uint(3), s.ConsecutiveFailures)
+			assert.Equal(t, 503, s.LastHTTPStatus)
+			assert.Contains(t, s.LastError, mockErr.Error())
+			assert.False(t, s.Healthy())
+		} else {
+			assert.Equal(t, mockFeeds[1].ID, s.FeedID)
+			assert.True(t, s.Healthy())
+			assert.False(t, s.LastSuccessAt.IsZero())
+		}
+	}
+
+	// The failing feed is backed off for 4 fetch periods after its third
+	// consecutive failure
+	assert.True(t, mockFeeds[0].NextFetchAt.After(time.Now().Add(3*mockCfg.FetchPeriod)))
+}
+
+func TestParseFeedsLimitsRequestsPerHost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockFeeds := make([]*feed.Feed, 4, 4)
+	for i := 0; i < len(mockFeeds); i++ {
+		mockFeeds[i] = &feed.Feed{
+			URL: fmt.Sprintf("https://example.com/%d", i),
+		}
+	}
+
+	var current, max int32
+	p := mock_parser.NewMockParser(ctrl)
+	p.EXPECT().ParseFeed(gomock.Any(), gomock.Any()).Times(len(mockFeeds)).DoAndReturn(func(ctx context.Context, f *feed.Feed) (*parser.Response, error) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		return mockResponse(nil), nil
+	})
+
+	var count int
+	for parsed := range parseFeeds(context.Background(), p, mockFeeds, 4, 2) {
+		assert.NoError(t, parsed.err)
+		count++
+	}
+
+	assert.Equal(t, len(mockFeeds), count)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&max))
+}
+
 func randTag() string {
 	return fmt.Sprintf("tag %v", rand.Int())
 }
@@ -365,6 +924,7 @@ func mockConfig(t *testing.T) *config.Config {
 
 func randFeed() *feed.Feed {
 	return &feed.Feed{
+		ID:  uint(rand.Uint32()),
 		URL: fmt.Sprintf("URL %d", rand.Int()),
 	}
 }
@@ -381,6 +941,45 @@ func mockFeeds() []*feed.Feed {
 	return randFeeds(2)
 }
 
+var (
+	anyItem     = gomock.AssignableToTypeOf(&feed.Item{})
+	anyFeed     = gomock.AssignableToTypeOf(&feed.Feed{})
+	anyStatus   = gomock.AssignableToTypeOf(&feed.Status{})
+	anyStatuses = gomock.AssignableToTypeOf(&[]*feed.Status{})
+)
+
+// Run transactions in the mock itself, so that the statements in them are
+// expected like any others
+func expectTransactions(mockDB *mock_db.MockDB) {
+	mockDB.EXPECT().TransactionContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(db.Tx) error) error {
+		return fn(mockDB)
+	}).AnyTimes()
+}
+
+// Expect the feed statuses to be loaded, and each fetched feed to be
+// rescheduled & saved along with its status
+func expectFeedsUpdated(mockDB *mock_db.MockDB, n int) {
+	expectTransactions(mockDB)
+	mockDB.EXPECT().AllContext(gomock.Any(), anyStatuses).Return(nil)
+	mockDB.EXPECT().FindAllContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(n)
+	mockDB.EXPECT().SaveContext(gomock.Any(), anyFeed).Return(nil).Times(n)
+	mockDB.EXPECT().SaveContext(gomock.Any(), anyStatus).Return(nil).Times(n)
+}
+
+func mockResponse(items []*feed.Item) *parser.Response {
+	return &parser.Response{
+		Items: items,
+	}
+}
+
+func notModifiedResponse(f *feed.Feed) *parser.Response {
+	return &parser.Response{
+		NotModified:  true,
+		ETag:         f.ETag,
+		LastModified: f.LastModified,
+	}
+}
+
 func mockError() error {
 	return fmt.Errorf("mock error")
 }
@@ -0,0 +1,261 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gonews/config"
+	"gonews/db"
+	"gonews/db/orm/query"
+	"gonews/db/orm/query/clause"
+	"gonews/feed"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Period between purges, unless configured
+	defaultRetentionPeriod = 24 * time.Hour
+
+	// Tombstones are deleted once they're older than this, by which time
+	// their items have usually dropped out of their feeds
+	tombstoneMaxAge = 365 * 24 * time.Hour
+
+	// Number of items deleted per statement, keeping under SQLite's limit
+	// on the number of parameters
+	purgeBatchSize = 500
+)
+
+// Find the tombstone of a purged item which the given item is another copy of,
+// matching it the same way as findExistingItem; returns nil if there's none
+func findTombstone(ctx context.Context, db db.Tx, f *feed.Feed, item *feed.Item) (*feed.Tombstone, error) {
+	for _, w := range itemMatchClauses(f, item) {
+		var tombstone feed.Tombstone
+		err := db.FindContext(ctx, &tombstone, w)
+		if err == nil {
+			return &tombstone, nil
+		} else if !errors.Is(err, query.ErrModelNotFound) {
+			return nil, fmt.Errorf("failed to get matching tombstone: %w", err)
+		}
+	}
+
+	return nil, nil
+}
+
+// Return whether the given item is a copy of one which was purged by the feed's
+// retention policy
+func isPurged(ctx context.Context, db db.Tx, f *feed.Feed, item *feed.Item) (bool, error) {
+	// Only feeds which have been purged can have tombstones
+	if f.PurgedAt.IsZero() {
+		return false, nil
+	}
+
+	tombstone, err := findTombstone(ctx, db, f, item)
+	if err != nil {
+		return false, err
+	}
+
+	return tombstone != nil, nil
+}
+
+// Get the items of the feed which its retention policy no longer keeps: hidden
+// items older than HiddenAfter, and all but the newest MaxItems items
+func expiredItems(ctx context.Context, db db.Tx, f *feed.Feed, rc config.RetentionConfig) ([]*feed.Item, error) {
+	var expired []*feed.Item
+	seen := make(map[uint]bool)
+
+	if rc.HiddenAfter != 0 {
+		var hidden []*feed.Item
+		err := db.FindAllContext(ctx, &hidden, clause.Where("feed_id = ? and hide = ?", f.ID, true))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hidden items from feed: %w", err)
+		}
+
+		for _, item := range hidden {
+			if time.Since(item.CreatedAt) > rc.HiddenAfter {
+				expired = append(expired, item)
+				seen[item.ID] = true
+			}
+		}
+	}
+
+	if rc.MaxItems != 0 {
+		var oldest []*feed.Item
+		err := db.FindAllContext(
+			ctx,
+			&oldest,
+			clause.Where("feed_id = ?", f.ID),
+			clause.OrderBy("id desc"),
+			clause.New("limit -1 offset ?", rc.MaxItems))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get oldest items from feed: %w", err)
+		}
+
+		for _, item := range oldest {
+			if !seen[item.ID] {
+				expired = append(expired, item)
+				seen[item.ID] = true
+			}
+		}
+	}
+
+	return expired, nil
+}
+
+// Delete the given items of the feed, along with their categories, enclosures,
+// revisions & alerts, leaving a tombstone for each
+func deleteItems(ctx context.Context, db db.Tx, f *feed.Feed, items []*feed.Item) error {
+	for start := 0; start < len(items); start += purgeBatchSize {
+		end := start + purgeBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		var ids []interface{}
+		for _, item := range items[start:end] {
+			tombstone := &feed.Tombstone{
+				FeedID: f.ID,
+				GUID:   item.GUID,
+				Link:   item.Link,
+				Hash:   item.Hash,
+			}
+			err := db.SaveContext(ctx, tombstone)
+			if err != nil {
+				return fmt.Errorf("failed to save tombstone: %w", err)
+			}
+
+			ids = append(ids, item.ID)
+		}
+
+		dependents := []interface{}{
+			&feed.ItemCategory{},
+			&feed.Enclosure{},
+			&feed.ItemAuthor{},
+			&feed.ItemRevision{},
+			&feed.Alert{},
+		}
+		for _, model := range dependents {
+			err := db.DeleteWhereContext(ctx, model, clause.Where("item_id"), clause.In(ids...))
+			if err != nil {
+				return fmt.Errorf("failed to delete dependents of items: %w", err)
+			}
+		}
+
+		err := db.DeleteWhereContext(ctx, &feed.Item{}, clause.Where("id"), clause.In(ids...))
+		if err != nil {
+			return fmt.Errorf("failed to delete items: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Delete the items which the feeds' retention policies no longer keep, and the
+// tombstones which have expired, returning the number of items deleted
+// The database is vacuumed afterwards if anything was deleted; fetches wait
+// until the purge is done, so that purged items aren't inserted in between
+func purgeItems(ctx context.Context, cfg *config.Config, adb db.DB) (uint, error) {
+	fetchMutex.Lock()
+	defer fetchMutex.Unlock()
+
+	var feeds []*feed.Feed
+	err := adb.AllContext(ctx, &feeds)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get feeds: %w", err)
+	}
+
+	var purged uint
+	for _, f := range feeds {
+		items, err := expiredItems(ctx, adb, f, cfg.FeedRetention(f.URL))
+		if err != nil {
+			return purged, err
+		} else if len(items) == 0 {
+			continue
+		}
+
+		// The tombstones are saved with the deletions, so that purged items
+		// can't be inserted again if the purge is interrupted
+		err = adb.TransactionContext(ctx, func(tx db.Tx) error {
+			err := deleteItems(ctx, tx, f, items)
+			if err != nil {
+				return err
+			}
+
+			f.PurgedAt = time.Now()
+			err = tx.SaveContext(ctx, f)
+			if err != nil {
+				return fmt.Errorf("failed to save feed: %w", err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+
+		log.Info().Msgf("purged %d items from %s", len(items), f.URL)
+		purged += uint(len(items))
+	}
+
+	var tombstones []*feed.Tombstone
+	err = adb.AllContext(ctx, &tombstones)
+	if err != nil {
+		return purged, fmt.Errorf("failed to get tombstones: %w", err)
+	}
+
+	var expired []interface{}
+	for _, t := range tombstones {
+		if time.Since(t.CreatedAt) > tombstoneMaxAge {
+			expired = append(expired, t.ID)
+		}
+	}
+
+	for start := 0; start < len(expired); start += purgeBatchSize {
+		end := start + purgeBatchSize
+		if end > len(expired) {
+			end = len(expired)
+		}
+
+		err = adb.DeleteWhereContext(ctx, &feed.Tombstone{}, clause.Where("id"), clause.In(expired[start:end]...))
+		if err != nil {
+			return purged, fmt.Errorf("failed to delete tombstones: %w", err)
+		}
+	}
+
+	if purged > 0 || len(expired) > 0 {
+		// The purge succeeded even if the space can't be reclaimed yet
+		err = adb.VacuumContext(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to vacuum DB after purge")
+		}
+	}
+
+	return purged, nil
+}
+
+// PurgeItems periodically deletes the items which the feeds' retention
+// policies no longer keep
+func PurgeItems(ctx context.Context, cfg *config.Config, db db.DB) error {
+	period := cfg.RetentionPeriod
+	if period == 0 {
+		period = defaultRetentionPeriod
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		_, err := purgeItems(ctx, cfg, db)
+		if err != nil {
+			return fmt.Errorf("failed to purge items: %w", err)
+		}
+
+		select {
+		case <-ticker.C:
+			break
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"gonews/config"
+	"gonews/db"
+	"gonews/db/orm/query/clause"
+	"gonews/feed"
+	"strings"
+	"time"
+)
+
+// RevisionDiff pairs a previous version of an item with the changes made to
+// it by the following version
+type RevisionDiff struct {
+	Revision    *feed.ItemRevision `json:"revision"`
+	Title       []*DiffOp          `json:"title"`
+	Description []*DiffOp          `json:"description"`
+}
+
+// DiffOp is a run of words which are unchanged ("="), inserted ("+") or
+// deleted ("-") between two versions of a text
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Return true if the feed changed the content of the existing item
+// Whitespace & case changes don't count, since they don't change the hash
+func itemChanged(existing, item *feed.Item) bool {
+	return item.Hash != "" && existing.Hash != item.Hash
+}
+
+// Store the existing item's content as a revision, and update the item with
+// the new content
+func reviseItem(ctx context.Context, cfg *config.Config, db db.Tx, existing, item *feed.Item) error {
+	rev := &feed.ItemRevision{
+		ItemID:      existing.ID,
+		Title:       existing.Title,
+		Description: existing.Description,
+		Link:        existing.Link,
+		Content:     existing.Content,
+		Hash:        existing.Hash,
+	}
+
+	err := db.SaveContext(ctx, rev)
+	if err != nil {
+		return fmt.Errorf("failed to save item revision: %w", err)
+	}
+
+	existing.Title = item.Title
+	existing.Description = item.Description
+	existing.Content = item.Content
+	existing.Updated = item.Updated
+	existing.Link = item.Link
+	existing.Hash = item.Hash
+	existing.RevisedAt = time.Now()
+	if cfg.UnhideUpdatedItems {
+		existing.Hide = false
+	}
+
+	err = db.SaveContext(ctx, existing)
+	if err != nil {
+		return fmt.Errorf("failed to save item: %w", err)
+	}
+
+	return nil
+}
+
+// Append the words to the diff, merging them into the last op if it's the
+// same kind
+func appendDiffOp(ops []*DiffOp, op string, word string) []*DiffOp {
+	if len(ops) > 0 && ops[len(ops)-1].Op == op {
+		ops[len(ops)-1].Text += " " + word
+		return ops
+	}
+
+	return append(ops, &DiffOp{Op: op, Text: word})
+}
+
+// Compute the word-level diff between two texts, from their longest common
+// subsequence of words
+func diffWords(a, b string) []*DiffOp {
+	aWords := strings.Fields(a)
+	bWords := strings.Fields(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// aWords[i:] & bWords[j:]
+	lcs := make([][]int, len(aWords)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bWords)+1)
+	}
+	for i := len(aWords) - 1; i >= 0; i-- {
+		for j := len(bWords) - 1; j >= 0; j-- {
+			if aWords[i] == bWords[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []*DiffOp
+	i, j := 0, 0
+	for i < len(aWords) && j < len(bWords) {
+		if aWords[i] == bWords[j] {
+			ops = appendDiffOp(ops, "=", aWords[i])
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			ops = appendDiffOp(ops, "-", aWords[i])
+			i++
+		} else {
+			ops = appendDiffOp(ops, "+", bWords[j])
+			j++
+		}
+	}
+	for ; i < len(aWords); i++ {
+		ops = appendDiffOp(ops, "-", aWords[i])
+	}
+	for ; j < len(bWords); j++ {
+		ops = appendDiffOp(ops, "+", bWords[j])
+	}
+
+	return ops
+}
+
+// ItemRevisions returns the previous versions of the item, oldest first, each
+// with its diff against the following version
+func ItemRevisions(ctx context.Context, db db.DB, itemID uint) ([]*RevisionDiff, error) {
+	var item feed.Item
+	err := db.FindContext(ctx, &item, clause.Where("id = ?", itemID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matching item: %w", err)
+	}
+
+	var revs []*feed.ItemRevision
+	err = db.FindAllContext(ctx, &revs, clause.Where("item_id = ?", itemID), clause.OrderBy("id asc"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item revisions: %w", err)
+	}
+
+	var diffs []*RevisionDiff
+	for i, rev := range revs {
+		nextTitle, nextDescription := item.Title, item.Description
+		if i+1 < len(revs) {
+			nextTitle, nextDescription = revs[i+1].Title, revs[i+1].Description
+		}
+
+		diffs = append(diffs, &RevisionDiff{
+			Revision:    rev,
+			Title:       diffWords(rev.Title, nextTitle),
+			Description: diffWords(rev.Description, nextDescription),
+		})
+	}
+
+	return diffs, nil
+}
@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"gonews/db/orm/query"
+	"gonews/db/orm/query/clause"
+	"gonews/feed"
+	"gonews/mock_db"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		ops  []*DiffOp
+	}{
+		{"empty", "", "", nil},
+		{"unchanged", "New TLS attack", "New  TLS\nattack", []*DiffOp{{Op: "=", Text: "New TLS attack"}}},
+		{"inserted", "", "New TLS attack", []*DiffOp{{Op: "+", Text: "New TLS attack"}}},
+		{"deleted", "New TLS attack", "", []*DiffOp{{Op: "-", Text: "New TLS attack"}}},
+		{
+			"replaced",
+			"Affects version 1",
+			"Affects versions 1 and 2",
+			[]*DiffOp{
+				{Op: "=", Text: "Affects"},
+				{Op: "-", Text: "version"},
+				{Op: "+", Text: "versions"},
+				{Op: "=", Text: "1"},
+				{Op: "+", Text: "and 2"},
+			},
+		},
+		{
+			"case",
+			"New TLS attack",
+			"New tls attack",
+			[]*DiffOp{
+				{Op: "=", Text: "New"},
+				{Op: "-", Text: "TLS"},
+				{Op: "+", Text: "tls"},
+				{Op: "=", Text: "attack"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.ops, diffWords(test.a, test.b), test.name)
+	}
+}
+
+func TestItemRevisions(t *testing.T) {
+	item := feed.Item{ID: 1, Title: "Advisory v3", Description: "Affects versions 1, 2 and 3"}
+
+	tests := []struct {
+		name  string
+		revs  []*feed.ItemRevision
+		diffs []*RevisionDiff
+	}{
+		{"no revisions", nil, nil},
+		{
+			"one revision",
+			[]*feed.ItemRevision{
+				{ID: 1, ItemID: 1, Title: "Advisory v2", Description: "Affects versions 1, 2 and 3"},
+			},
+			[]*RevisionDiff{
+				{
+					Title: []*DiffOp{
+						{Op: "=", Text: "Advisory"},
+						{Op: "-", Text: "v2"},
+						{Op: "+", Text: "v3"},
+					},
+					Description: []*DiffOp{{Op: "=", Text: "Affects versions 1, 2 and 3"}},
+				},
+			},
+		},
+		{
+			"several revisions",
+			[]*feed.ItemRevision{
+				{ID: 1, ItemID: 1, Title: "Advisory", Description: "Affects version 1"},
+				{ID: 2, ItemID: 1, Title: "Advisory v2", Description: "Affects versions 1, 2 and 3"},
+			},
+			[]*RevisionDiff{
+				{
+					Title: []*DiffOp{
+						{Op: "=", Text: "Advisory"},
+						{Op: "+", Text: "v2"},
+					},
+					Description: []*DiffOp{
+						{Op: "=", Text: "Affects"},
+						{Op: "-", Text: "version 1"},
+						{Op: "+", Text: "versions 1, 2 and 3"},
+					},
+				},
+				{
+					Title: []*DiffOp{
+						{Op: "=", Text: "Advisory"},
+						{Op: "-", Text: "v2"},
+						{Op: "+", Text: "v3"},
+					},
+					Description: []*DiffOp{{Op: "=", Text: "Affects versions 1, 2 and 3"}},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ctrl := gomock.NewController(t)
+
+		db := mock_db.NewMockDB(ctrl)
+		db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
+			*ptr.(*feed.Item) = item
+			return nil
+		})
+		db.EXPECT().FindAllContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...*clause.Clause) error {
+			*ptr.(*[]*feed.ItemRevision) = test.revs
+			return nil
+		})
+
+		for i, diff := range test.diffs {
+			diff.Revision = test.revs[i]
+		}
+
+		diffs, err := ItemRevisions(context.Background(), db, item.ID)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.diffs, diffs, test.name)
+
+		ctrl.Finish()
+	}
+}
+
+func TestItemRevisionsReturnsErrorWhenItemIsMissing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mock_db.NewMockDB(ctrl)
+	db.EXPECT().FindContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(query.ErrModelNotFound)
+
+	_, err := ItemRevisions(context.Background(), db, 1)
+	assert.True(t, errors.Is(err, query.ErrModelNotFound))
+}
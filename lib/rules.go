@@ -0,0 +1,156 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gonews/config"
+	"gonews/db"
+	"gonews/db/orm/query"
+	"gonews/db/orm/query/clause"
+	"gonews/feed"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Matches HTML tags, which are removed from descriptions before matching, so
+// that rules only match their text
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// rule is a filter rule compiled from its config
+type rule struct {
+	config   *config.RuleConfig
+	fields   []string
+	keywords []string
+	regex    *regexp.Regexp
+}
+
+func compileRules(rcs []*config.RuleConfig) ([]*rule, error) {
+	var rules []*rule
+	for _, rc := range rcs {
+		r := &rule{
+			config: rc,
+			fields: rc.Fields,
+		}
+		if len(r.fields) == 0 {
+			r.fields = config.RuleFields
+		}
+
+		for _, keyword := range rc.Keywords {
+			r.keywords = append(r.keywords, strings.ToLower(keyword))
+		}
+
+		if rc.Regex != "" {
+			regex, err := regexp.Compile(rc.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile rule regex: %w", err)
+			}
+			r.regex = regex
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// Return the unescaped text of the item's field
+func ruleFieldText(item *feed.Item, field string) string {
+	switch field {
+	case "title":
+		return html.UnescapeString(item.Title)
+	case "description":
+		return html.UnescapeString(htmlTagPattern.ReplaceAllString(item.Description, " "))
+	case "author":
+		return html.UnescapeString(strings.TrimSpace(item.Name + " " + item.Email))
+	case "link":
+		return html.UnescapeString(item.Link)
+	}
+
+	return ""
+}
+
+func (r *rule) matches(item *feed.Item) bool {
+	if len(r.keywords) == 0 && r.regex == nil {
+		return true
+	}
+
+	for _, field := range r.fields {
+		text := ruleFieldText(item, field)
+
+		lowerText := strings.ToLower(text)
+		for _, keyword := range r.keywords {
+			if strings.Contains(lowerText, keyword) {
+				return true
+			}
+		}
+
+		if r.regex != nil && r.regex.MatchString(text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RuleMatch contains the outcome of applying a feed's filter rules to an item
+// Rule is the first rule which matched the item, or nil if none did, in which
+// case the item is kept
+type RuleMatch struct {
+	Item   *feed.Item         `json:"item"`
+	Action string             `json:"action"`
+	Rule   *config.RuleConfig `json:"rule"`
+}
+
+// Apply the rules to the item, in order, until one matches
+func applyRules(rules []*rule, item *feed.Item) *RuleMatch {
+	for _, r := range rules {
+		if r.matches(item) {
+			return &RuleMatch{
+				Item:   item,
+				Action: r.config.Action,
+				Rule:   r.config,
+			}
+		}
+	}
+
+	return &RuleMatch{
+		Item:   item,
+		Action: config.RuleKeep,
+	}
+}
+
+// TestRules applies the filter rules configured for the feed with the given
+// URL to its stored items, so that rules can be tried before they're deployed
+// Only the items matched by a rule are returned; nothing is saved
+func TestRules(ctx context.Context, cfg *config.Config, db db.DB, feedURL string) ([]*RuleMatch, error) {
+	rules, err := compileRules(cfg.FeedRules(feedURL))
+	if err != nil {
+		return nil, err
+	}
+
+	var f feed.Feed
+	err = db.FindContext(ctx, &f, clause.Where("url = ?", feedURL))
+	if errors.Is(err, query.ErrModelNotFound) {
+		return nil, fmt.Errorf("feed not found: %s", feedURL)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+
+	var items []*feed.Item
+	err = db.FindAllContext(ctx, &items, clause.Where("feed_id = ?", f.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items from feed: %w", err)
+	}
+
+	var matches []*RuleMatch
+	for _, item := range items {
+		match := applyRules(rules, item)
+		if match.Rule != nil {
+			matches = append(matches, match)
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"gonews/config"
+	"gonews/feed"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyRulesReturnsFirstMatchingRule(t *testing.T) {
+	rules, err := compileRules([]*config.RuleConfig{
+		{Action: config.RuleKeep, Fields: []string{"title"}, Keywords: []string{"Show HN: Exploit"}},
+		{Action: config.RuleDrop, Fields: []string{"title"}, Regex: `^(Show|Ask) HN`},
+		{Action: config.RuleHide, Keywords: []string{"bitcoin"}},
+		{Action: config.RuleDrop, Fields: []string{"author"}, Keywords: []string{"spam@example.com"}},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		item   *feed.Item
+		action string
+	}{
+		{&feed.Item{Title: "Show HN: Exploit kit scanner"}, config.RuleKeep},
+		{&feed.Item{Title: "Show HN: My weekend project"}, config.RuleDrop},
+		{&feed.Item{Title: "Ask HN: Salaries?"}, config.RuleDrop},
+		{&feed.Item{Title: "Exchange hacked", Description: "<p>Thousands of <b>Bitcoin</b> stolen</p>"}, config.RuleHide},
+		{&feed.Item{Title: "Exchange hacked", Link: "https://example.com/bitcoin"}, config.RuleHide},
+		{&feed.Item{Title: "Deal", Email: "spam@example.com"}, config.RuleDrop},
+		{&feed.Item{Title: "New TLS attack"}, config.RuleKeep},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.action, applyRules(rules, test.item).Action, test.item.Title)
+	}
+
+	assert.Nil(t, applyRules(rules, &feed.Item{Title: "New TLS attack"}).Rule)
+}
+
+func TestApplyRulesMatchesUnescapedText(t *testing.T) {
+	rules, err := compileRules([]*config.RuleConfig{
+		{Action: config.RuleDrop, Fields: []string{"description"}, Keywords: []string{"b&w"}},
+	})
+	assert.NoError(t, err)
+
+	// Tag names & attributes aren't matched
+	match := applyRules(rules, &feed.Item{Description: `<img alt="b&amp;w">Photos`})
+	assert.Equal(t, config.RuleKeep, match.Action)
+
+	match = applyRules(rules, &feed.Item{Description: `<p>Photos in b&amp;w</p>`})
+	assert.Equal(t, config.RuleDrop, match.Action)
+}
+
+func TestApplyRulesWithoutMatchersMatchesEverything(t *testing.T) {
+	rules, err := compileRules([]*config.RuleConfig{
+		{Action: config.RuleKeep, Keywords: []string{"security"}},
+		{Action: config.RuleDrop},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, config.RuleKeep, applyRules(rules, &feed.Item{Title: "Security news"}).Action)
+	assert.Equal(t, config.RuleDrop, applyRules(rules, &feed.Item{Title: "Other news"}).Action)
+}
@@ -0,0 +1,155 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"gonews/config"
+	"gonews/db"
+	"gonews/db/orm/query/clause"
+	"gonews/feed"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// Period between fetches of feeds without a fetch period or publish
+	// history, unless configured
+	defaultFetchPeriod = 12 * time.Hour
+
+	// Lower bound on the wait between fetches, so that feeds which are
+	// always due, ex. because they can't be rescheduled, aren't fetched in a
+	// loop
+	minFetchWait = time.Second
+
+	// Lower bound on adaptive fetch periods, so that feeds which publish in
+	// bursts aren't polled continuously
+	minAdaptiveFetchPeriod = 5 * time.Minute
+
+	// Number of recent items used to estimate how often a feed publishes
+	adaptiveSampleSize = 10
+
+	// Maximum fraction of the fetch period added as jitter, to keep feeds
+	// fetched at the same time from staying in lockstep
+	fetchJitter = 0.1
+
+	// Upper bound on the delay before retrying a failing feed
+	maxFetchBackoff = 7 * 24 * time.Hour
+)
+
+// Return the configured global fetch period, or the default if it's unset
+func globalFetchPeriod(cfg *config.Config) time.Duration {
+	if cfg.FetchPeriod == 0 {
+		return defaultFetchPeriod
+	}
+
+	return cfg.FetchPeriod
+}
+
+// Estimate how often a feed publishes from the median interval between the
+// given publish times, bounded by the global fetch period
+// Falls back to the global fetch period if there's too little history
+func adaptiveFetchPeriod(published []time.Time, maxPeriod time.Duration) time.Duration {
+	sort.Slice(published, func(i, j int) bool {
+		return published[i].After(published[j])
+	})
+
+	var intervals []time.Duration
+	for i := 1; i < len(published); i++ {
+		if published[i].IsZero() {
+			break
+		}
+
+		intervals = append(intervals, published[i-1].Sub(published[i]))
+	}
+
+	if len(intervals) == 0 {
+		return maxPeriod
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i] < intervals[j]
+	})
+
+	period := intervals[len(intervals)/2]
+	if period < minAdaptiveFetchPeriod {
+		period = minAdaptiveFetchPeriod
+	}
+
+	if maxPeriod > 0 && period > maxPeriod {
+		period = maxPeriod
+	}
+
+	return period
+}
+
+// Double the period for each consecutive failure after the first, up to the
+// maximum backoff
+func backoff(period time.Duration, failures uint) time.Duration {
+	for i := uint(1); i < failures && period < maxFetchBackoff; i++ {
+		period *= 2
+	}
+
+	if period > maxFetchBackoff {
+		period = maxFetchBackoff
+	}
+
+	return period
+}
+
+func withJitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Float64()*fetchJitter*float64(d))
+}
+
+// Determine how long to wait before fetching the feed again
+// Uses the feed's configured period if present, and otherwise adapts to how
+// often the feed's stored items were published
+func feedFetchPeriod(ctx context.Context, cfg *config.Config, db db.DB, f *feed.Feed) (time.Duration, error) {
+	if f.FetchPeriod != 0 {
+		return f.FetchPeriod, nil
+	}
+
+	var items []*feed.Item
+	err := db.FindAllContext(
+		ctx,
+		&items,
+		clause.Where("feed_id = ?", f.ID),
+		clause.OrderBy("published desc"),
+		clause.Limit(adaptiveSampleSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recent items: %w", err)
+	}
+
+	var published []time.Time
+	for _, item := range items {
+		published = append(published, item.Published)
+	}
+
+	return adaptiveFetchPeriod(published, globalFetchPeriod(cfg)), nil
+}
+
+// Set the time at which the feed should next be fetched, backing off if the
+// feed has been failing
+func scheduleFeed(ctx context.Context, cfg *config.Config, db db.DB, f *feed.Feed, failures uint) error {
+	period, err := feedFetchPeriod(ctx, cfg, db, f)
+	if err != nil {
+		return err
+	}
+
+	f.NextFetchAt = time.Now().Add(withJitter(backoff(period, failures)))
+
+	return nil
+}
+
+// Return the time at which the earliest of the given feeds is due, or one
+// global fetch period from now if there are no feeds
+func nextFetchAt(cfg *config.Config, feeds []*feed.Feed) time.Time {
+	next := time.Now().Add(globalFetchPeriod(cfg))
+	for _, f := range feeds {
+		if f.NextFetchAt.Before(next) {
+			next = f.NextFetchAt
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"gonews/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveFetchPeriodUsesMedianPublishInterval(t *testing.T) {
+	now := time.Now()
+	published := []time.Time{
+		now.Add(-1 * time.Hour),
+		now,
+		now.Add(-3 * time.Hour),
+		now.Add(-10 * time.Hour),
+	}
+
+	period := adaptiveFetchPeriod(published, 24*time.Hour)
+	assert.Equal(t, 2*time.Hour, period)
+}
+
+func TestAdaptiveFetchPeriodIsBoundedByMaxPeriod(t *testing.T) {
+	now := time.Now()
+	published := []time.Time{
+		now,
+		now.Add(-48 * time.Hour),
+	}
+
+	period := adaptiveFetchPeriod(published, 12*time.Hour)
+	assert.Equal(t, 12*time.Hour, period)
+}
+
+func TestAdaptiveFetchPeriodIsBoundedByMinPeriod(t *testing.T) {
+	now := time.Now()
+	published := []time.Time{
+		now,
+		now.Add(-1 * time.Second),
+		now.Add(-2 * time.Second),
+	}
+
+	period := adaptiveFetchPeriod(published, 12*time.Hour)
+	assert.Equal(t, minAdaptiveFetchPeriod, period)
+}
+
+func TestAdaptiveFetchPeriodReturnsMaxPeriodWithoutHistory(t *testing.T) {
+	period := adaptiveFetchPeriod([]time.Time{time.Now()}, 12*time.Hour)
+	assert.Equal(t, 12*time.Hour, period)
+
+	period = adaptiveFetchPeriod([]time.Time{time.Now(), {}}, 12*time.Hour)
+	assert.Equal(t, 12*time.Hour, period)
+}
+
+func TestWithJitterAddsAtMostJitterFraction(t *testing.T) {
+	d := time.Hour
+	for i := 0; i < 100; i++ {
+		jittered := withJitter(d)
+		assert.True(t, jittered >= d)
+		assert.True(t, jittered <= d+time.Duration(fetchJitter*float64(d)))
+	}
+}
+
+func TestBackoffDoublesPeriodForEachConsecutiveFailure(t *testing.T) {
+	assert.Equal(t, time.Hour, backoff(time.Hour, 0))
+	assert.Equal(t, time.Hour, backoff(time.Hour, 1))
+	assert.Equal(t, 2*time.Hour, backoff(time.Hour, 2))
+	assert.Equal(t, 8*time.Hour, backoff(time.Hour, 4))
+}
+
+func TestBackoffIsBoundedByMaxBackoff(t *testing.T) {
+	assert.Equal(t, maxFetchBackoff, backoff(time.Hour, 100))
+	assert.Equal(t, maxFetchBackoff, backoff(2*maxFetchBackoff, 1))
+}
+
+func TestNextFetchAtUsesDefaultFetchPeriodWithoutFeeds(t *testing.T) {
+	before := time.Now()
+	next := nextFetchAt(&config.Config{}, nil)
+	assert.False(t, next.Before(before.Add(defaultFetchPeriod)))
+
+	next = nextFetchAt(&config.Config{FetchPeriod: time.Hour}, nil)
+	assert.False(t, next.Before(before.Add(time.Hour)))
+	assert.True(t, next.Before(before.Add(defaultFetchPeriod)))
+}
@@ -0,0 +1,175 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gonews/config"
+	"gonews/db"
+	"gonews/db/orm/query"
+	"gonews/db/orm/query/clause"
+	"gonews/feed"
+	"gonews/parser"
+	"gonews/websub"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// How often subscriptions are checked for renewal
+	websubRenewPeriod = time.Hour
+
+	// How long before its lease expires a subscription is renewed
+	websubRenewMargin = 24 * time.Hour
+
+	// How long to wait for the hub to verify a subscription before
+	// requesting it again
+	websubRetryPeriod = websub.VerifyPeriod
+)
+
+// Record a subscription to the hub advertised by the feed, replacing any
+// existing subscription if the hub or topic changed
+// The subscription is requested from the hub by RenewSubscriptions; until the
+// hub verifies it, the feed continues to be polled as usual
+func ensureSubscription(ctx context.Context, db db.DB, f *feed.Feed, links parser.Links) error {
+	topic := links.Self
+	if topic == "" {
+		topic = f.URL
+	}
+
+	var sub websub.Subscription
+	err := db.FindContext(ctx, &sub, clause.Where("feed_id = ?", f.ID))
+	if err != nil && !errors.Is(err, query.ErrModelNotFound) {
+		return fmt.Errorf("failed to get matching subscription: %w", err)
+	} else if err == nil && sub.Hub == links.Hub && sub.Topic == topic {
+		return nil
+	}
+
+	secret, err := websub.NewSecret()
+	if err != nil {
+		return err
+	}
+
+	token, err := websub.NewToken()
+	if err != nil {
+		return err
+	}
+
+	sub.FeedID = f.ID
+	sub.Hub = links.Hub
+	sub.Topic = topic
+	sub.Secret = secret
+	sub.Token = token
+	sub.RequestedAt = time.Time{}
+	sub.ExpiresAt = time.Time{}
+
+	err = db.SaveContext(ctx, &sub)
+	if err != nil {
+		return fmt.Errorf("failed to save subscription: %w", err)
+	}
+
+	log.Info().Msgf("discovered hub: %s", sub)
+
+	return nil
+}
+
+// Return true if the subscription should be requested from the hub, either
+// because it isn't active or because its lease is about to expire
+func subscriptionDue(sub *websub.Subscription) bool {
+	if time.Since(sub.RequestedAt) < websubRetryPeriod {
+		return false
+	}
+
+	return time.Until(sub.ExpiresAt) < websubRenewMargin
+}
+
+// Request each subscription which is due from its hub
+func renewSubscriptions(ctx context.Context, cfg *config.Config, db db.DB, client *http.Client) error {
+	var subs []*websub.Subscription
+	err := db.AllContext(ctx, &subs)
+	if err != nil {
+		return fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+
+	for _, sub := range subs {
+		if !subscriptionDue(sub) {
+			continue
+		}
+
+		sub.RequestedAt = time.Now()
+		err = db.SaveContext(ctx, sub)
+		if err != nil {
+			return fmt.Errorf("failed to save subscription: %w", err)
+		}
+
+		// A hub being unreachable shouldn't prevent subscribing to the
+		// others; the request is retried after the retry period
+		err = websub.Subscribe(client, sub, sub.CallbackURL(cfg.WebSubCallbackURL), cfg.WebSubLease)
+		if err != nil {
+			log.Error().Err(err).Msgf("Failed to subscribe: %s", sub)
+			continue
+		}
+
+		log.Info().Msgf("requested: %s", sub)
+	}
+
+	return nil
+}
+
+// RenewSubscriptions periodically requests new and expiring WebSub
+// subscriptions from their hubs
+func RenewSubscriptions(ctx context.Context, cfg *config.Config, db db.DB) error {
+	client := &http.Client{Timeout: time.Minute}
+
+	ticker := time.NewTicker(websubRenewPeriod)
+	defer ticker.Stop()
+
+	for {
+		err := renewSubscriptions(ctx, cfg, db, client)
+		if err != nil {
+			return fmt.Errorf("failed to renew subscriptions: %w", err)
+		}
+
+		select {
+		case <-ticker.C:
+			break
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// ReceiveContent returns a websub.ContentFunc which parses the content pushed
+// by a hub and saves its items to the subscribed feed
+// The items are saved the same way as fetched ones, so pushes only wait for
+// other writes of items, not for fetches to download their feeds
+func ReceiveContent(cfg *config.Config, p parser.Parser) websub.ContentFunc {
+	return func(ctx context.Context, db db.DB, sub *websub.Subscription, body []byte) error {
+		var f feed.Feed
+		err := db.FindContext(ctx, &f, clause.Where("id = ?", sub.FeedID))
+		if err != nil {
+			return fmt.Errorf("failed to get matching feed: %w", err)
+		}
+
+		items, err := p.Parse(bytes.NewReader(body), f.URL)
+		if err != nil {
+			return err
+		}
+
+		res := &FeedResult{
+			FeedID: f.ID,
+			URL:    f.URL,
+		}
+		res.Err = saveFeedItems(ctx, cfg, db, p, &f, items, res)
+		if res.Err != nil {
+			return res.Err
+		}
+
+		log.Info().Msgf("received: %s", res)
+
+		return nil
+	}
+}
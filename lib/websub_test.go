@@ -0,0 +1,218 @@
+package lib
+
+import (
+	"context"
+	"gonews/db/orm/query"
+	"gonews/feed"
+	"gonews/mock_db"
+	"gonews/mock_parser"
+	"gonews/parser"
+	"gonews/test"
+	"gonews/websub"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var anySubscription = gomock.AssignableToTypeOf(&websub.Subscription{})
+
+func mockHubResponse() *parser.Response {
+	return &parser.Response{
+		Links: parser.Links{
+			Hub:  "https://hub.example.com/",
+			Self: "https://example.com/feed.xml",
+		},
+	}
+}
+
+func expectFeedFetched(t *testing.T, db *mock_db.MockDB, f *feed.Feed) {
+	db.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		feeds, ok := ptr.(*[]*feed.Feed)
+		assert.True(t, ok)
+
+		*feeds = []*feed.Feed{f}
+
+		return nil
+	})
+	expectFeedsUpdated(db, 1)
+}
+
+func TestFetchFeedsSubscribesToAdvertisedHub(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockCfg.WebSubCallbackURL = "https://gonews.example.com/websub"
+	mockFeed := randFeed()
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockHubResponse(), nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	expectFeedFetched(t, db, mockFeed)
+	db.EXPECT().FindContext(gomock.Any(), anySubscription, gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anySubscription).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		sub := ptr.(*websub.Subscription)
+		assert.Equal(t, mockFeed.ID, sub.FeedID)
+		assert.Equal(t, "https://hub.example.com/", sub.Hub)
+		assert.Equal(t, "https://example.com/feed.xml", sub.Topic)
+		assert.NotEmpty(t, sub.Secret)
+		assert.NotEmpty(t, sub.Token)
+
+		return nil
+	})
+
+	_, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+}
+
+func TestFetchFeedsKeepsExistingSubscription(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockCfg.WebSubCallbackURL = "https://gonews.example.com/websub"
+	mockFeed := randFeed()
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockHubResponse(), nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	expectFeedFetched(t, db, mockFeed)
+	db.EXPECT().FindContext(gomock.Any(), anySubscription, gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...interface{}) error {
+		sub := ptr.(*websub.Subscription)
+		sub.FeedID = mockFeed.ID
+		sub.Hub = "https://hub.example.com/"
+		sub.Topic = "https://example.com/feed.xml"
+
+		return nil
+	})
+
+	_, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+}
+
+func TestFetchFeedsIgnoresHubWhenWebSubDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCfg := mockConfig(t)
+	mockFeed := randFeed()
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().ParseFeed(gomock.Any(), mockFeed).Return(mockHubResponse(), nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	expectFeedFetched(t, db, mockFeed)
+
+	_, err := fetchFeeds(context.Background(), mockCfg, db, parser)
+	assert.NoError(t, err)
+}
+
+func TestSubscriptionDue(t *testing.T) {
+	now := time.Now()
+
+	assert.True(t, subscriptionDue(&websub.Subscription{}))
+	assert.False(t, subscriptionDue(&websub.Subscription{
+		RequestedAt: now,
+	}))
+	assert.True(t, subscriptionDue(&websub.Subscription{
+		RequestedAt: now.Add(-2 * websubRetryPeriod),
+	}))
+	assert.False(t, subscriptionDue(&websub.Subscription{
+		RequestedAt: now.Add(-2 * websubRetryPeriod),
+		ExpiresAt:   now.Add(2 * websubRenewMargin),
+	}))
+	assert.True(t, subscriptionDue(&websub.Subscription{
+		RequestedAt: now.Add(-2 * websubRetryPeriod),
+		ExpiresAt:   now.Add(websubRenewMargin / 2),
+	}))
+}
+
+func TestReceiveContentSavesItems(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockFeed := randFeed()
+	mockItems := test.MockItems()[:1]
+	body := []byte("content")
+
+	parser := mock_parser.NewMockParser(ctrl)
+	parser.EXPECT().Parse(gomock.Any(), mockFeed.URL).Return(mockItems, nil)
+
+	db := mock_db.NewMockDB(ctrl)
+	expectTransactions(db)
+	db.EXPECT().FindContext(gomock.Any(), anyFeed, gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...interface{}) error {
+		*ptr.(*feed.Feed) = *mockFeed
+		return nil
+	})
+	db.EXPECT().FindContext(gomock.Any(), anyItem, gomock.Any()).Return(query.ErrModelNotFound)
+	db.EXPECT().SaveContext(gomock.Any(), anyItem).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		assert.Equal(t, mockFeed.ID, ptr.(*feed.Item).FeedID)
+		return nil
+	})
+
+	err := ReceiveContent(mockConfig(t), parser)(context.Background(), db, &websub.Subscription{FeedID: mockFeed.ID}, body)
+	assert.NoError(t, err)
+}
+
+func TestReceiveContentDoesNotWaitForFetches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	fetchedFeed := randFeed()
+	parsing := make(chan struct{})
+	release := make(chan struct{})
+
+	fetchParser := mock_parser.NewMockParser(ctrl)
+	fetchParser.EXPECT().ParseFeed(gomock.Any(), fetchedFeed).DoAndReturn(func(ctx context.Context, f *feed.Feed) (*parser.Response, error) {
+		close(parsing)
+		<-release
+		return notModifiedResponse(f), nil
+	})
+
+	fetchDB := mock_db.NewMockDB(ctrl)
+	fetchDB.EXPECT().AllContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}) error {
+		*ptr.(*[]*feed.Feed) = []*feed.Feed{fetchedFeed}
+		return nil
+	})
+	expectFeedsUpdated(fetchDB, 1)
+
+	fetched := make(chan struct{})
+	go func() {
+		defer close(fetched)
+
+		_, err := fetchFeeds(context.Background(), mockConfig(t), fetchDB, fetchParser)
+		assert.NoError(t, err)
+	}()
+	defer func() {
+		close(release)
+		<-fetched
+	}()
+
+	<-parsing
+
+	pushedFeed := randFeed()
+
+	pushParser := mock_parser.NewMockParser(ctrl)
+	pushParser.EXPECT().Parse(gomock.Any(), pushedFeed.URL).Return(test.MockItems()[:1], nil)
+
+	pushDB := mock_db.NewMockDB(ctrl)
+	expectTransactions(pushDB)
+	pushDB.EXPECT().FindContext(gomock.Any(), anyFeed, gomock.Any()).DoAndReturn(func(ctx context.Context, ptr interface{}, clauses ...interface{}) error {
+		*ptr.(*feed.Feed) = *pushedFeed
+		return nil
+	})
+	pushDB.EXPECT().FindContext(gomock.Any(), anyItem, gomock.Any()).Return(query.ErrModelNotFound)
+	pushDB.EXPECT().SaveContext(gomock.Any(), anyItem).Return(nil)
+
+	// The fetch is still downloading its feed while the content is pushed
+	received := make(chan error)
+	go func() {
+		received <- ReceiveContent(mockConfig(t), pushParser)(context.Background(), pushDB, &websub.Subscription{FeedID: pushedFeed.ID}, []byte("content"))
+	}()
+
+	select {
+	case err := <-received:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReceiveContent waited for the fetch in progress")
+	}
+}
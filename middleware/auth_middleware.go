@@ -2,36 +2,34 @@ package middleware
 
 import (
 	"gonews/auth"
-	"gonews/config"
 	"gonews/db"
 	"net/http"
 
 	"github.com/rs/zerolog/log"
 )
 
-func AuthMiddlewareFunc(h http.Handler) (http.Handler, error) {
+// NewAuthMiddlewareFunc creates a MiddlewareFunc which requires requests to
+// have the basic auth credentials of a user in the given DB
+func NewAuthMiddlewareFunc(db db.DB) MiddlewareFunc {
+	return func(h http.Handler) (http.Handler, error) {
+		return authHandler(db, h), nil
+	}
+}
+
+func authHandler(db db.DB, h http.Handler) http.Handler {
 	var handlerFunc http.HandlerFunc = func(
 		w http.ResponseWriter,
 		r *http.Request) {
-		dbCfg := config.DBConfigInst()
-
-		db, err := db.New(dbCfg)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to create db client")
-			return
-		}
-
-		defer db.Close()
-
-		// Advance declaration for goto
+		// Advance declarations for goto
 		var isValid bool
+		var err error
 
 		username, password, ok := r.BasicAuth()
 		if !ok {
 			goto loginFailed
 		}
 
-		isValid, err = auth.IsValid(username, password, db)
+		isValid, err = auth.IsValid(r.Context(), username, password, db)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to validate login")
 			goto loginFailed
@@ -53,5 +51,5 @@ func AuthMiddlewareFunc(h http.Handler) (http.Handler, error) {
 		}
 	}
 
-	return handlerFunc, nil
+	return handlerFunc
 }
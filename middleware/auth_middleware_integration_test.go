@@ -2,7 +2,6 @@ package middleware_test // 'middleware_test' instead of 'middleware' to prevent
 
 import (
 	"gonews/auth"
-	"gonews/config"
 	"gonews/middleware"
 	"gonews/test"
 	"gonews/user"
@@ -19,11 +18,11 @@ var (
 )
 
 func TestAuthMiddlewareReturnsUnauthorizedWhenCredsMissing(t *testing.T) {
-	authMiddlewareHandler, err := middleware.AuthMiddlewareFunc(nil)
-	assert.NoError(t, err)
+	_, db := test.InitDB(t, migrationsDir)
+	defer db.Close()
 
-	dbCfg, _ := test.InitDB(t, migrationsDir)
-	config.SetDBConfigInst(dbCfg)
+	authMiddlewareHandler, err := middleware.NewAuthMiddlewareFunc(db)(nil)
+	assert.NoError(t, err)
 
 	req := httptest.NewRequest("GET", "http://example.com/", nil)
 	w := httptest.NewRecorder()
@@ -33,11 +32,11 @@ func TestAuthMiddlewareReturnsUnauthorizedWhenCredsMissing(t *testing.T) {
 }
 
 func TestAuthMiddlewareReturnsUnauthorizedWhenCredsInvalid(t *testing.T) {
-	authMiddlewareHandler, err := middleware.AuthMiddlewareFunc(nil)
-	assert.NoError(t, err)
+	_, db := test.InitDB(t, migrationsDir)
+	defer db.Close()
 
-	dbCfg, _ := test.InitDB(t, migrationsDir)
-	config.SetDBConfigInst(dbCfg)
+	authMiddlewareHandler, err := middleware.NewAuthMiddlewareFunc(db)(nil)
+	assert.NoError(t, err)
 
 	req := httptest.NewRequest("GET", "http://example.com/", nil)
 
@@ -58,11 +57,11 @@ func TestAuthMiddlewareCallsNextHandlerWhenCredsValid(t *testing.T) {
 		r *http.Request) {
 		w.Write([]byte(mockResponseText))
 	}
-	authMiddlewareHandler, err := middleware.AuthMiddlewareFunc(mockHandlerFunc)
-	assert.NoError(t, err)
+	_, db := test.InitDB(t, migrationsDir)
+	defer db.Close()
 
-	dbCfg, db := test.InitDB(t, migrationsDir)
-	config.SetDBConfigInst(dbCfg)
+	authMiddlewareHandler, err := middleware.NewAuthMiddlewareFunc(db)(mockHandlerFunc)
+	assert.NoError(t, err)
 
 	req := httptest.NewRequest("GET", "http://example.com/", nil)
 
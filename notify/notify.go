@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gonews/config"
+	"gonews/feed"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Timeout of webhook requests
+const webhookTimeout = 30 * time.Second
+
+// Notifier sends an alert raised for a watchlist, ex. to a chat channel
+type Notifier interface {
+	Notify(alert *feed.Alert, item *feed.Item) error
+}
+
+// New creates a Notifier which sends alerts to each of the configured
+// notifiers
+func New(cfg *config.Config) (Notifier, error) {
+	var notifiers multiNotifier
+	for _, nc := range cfg.Notifiers {
+		switch nc.Type {
+		case config.NotifierLog:
+			notifiers = append(notifiers, &logNotifier{})
+		case config.NotifierWebhook:
+			notifiers = append(notifiers, NewWebhook(nc.URL, &http.Client{Timeout: webhookTimeout}))
+		default:
+			return nil, fmt.Errorf("invalid notifier type %q", nc.Type)
+		}
+	}
+
+	return notifiers, nil
+}
+
+// multiNotifier sends alerts to each of its notifiers
+type multiNotifier []Notifier
+
+// Every notifier is tried, even if an earlier one fails; the first error is
+// returned
+func (m multiNotifier) Notify(alert *feed.Alert, item *feed.Item) error {
+	var firstErr error
+	for _, n := range m {
+		err := n.Notify(alert, item)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// logNotifier writes alerts to the log
+type logNotifier struct{}
+
+func (n *logNotifier) Notify(alert *feed.Alert, item *feed.Item) error {
+	log.Warn().Msgf("alert: %s matched %q: %s", alert.Watchlist, alert.Term, item.Title)
+	return nil
+}
+
+// Payload is the JSON body which webhook notifiers POST for each alert
+type Payload struct {
+	Alert *feed.Alert `json:"alert"`
+	Item  *feed.Item  `json:"item"`
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a Notifier which POSTs each alert, with its item, as a
+// JSON Payload to the URL
+func NewWebhook(url string, client *http.Client) Notifier {
+	return &webhookNotifier{
+		url:    url,
+		client: client,
+	}
+}
+
+func (n *webhookNotifier) Notify(alert *feed.Alert, item *feed.Item) error {
+	body, err := json.Marshal(&Payload{Alert: alert, Item: item})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"gonews/config"
+	"gonews/feed"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookPostsAlert(t *testing.T) {
+	var received Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	n, err := New(&config.Config{
+		Notifiers: []*config.NotifierConfig{
+			{Type: config.NotifierLog},
+			{Type: config.NotifierWebhook, URL: server.URL},
+		},
+	})
+	assert.NoError(t, err)
+
+	err = n.Notify(
+		&feed.Alert{ItemID: 1, Watchlist: "products", Term: "gonews"},
+		&feed.Item{ID: 1, Title: "gonews released"})
+	assert.NoError(t, err)
+	assert.Equal(t, "products", received.Alert.Watchlist)
+	assert.Equal(t, "gonews", received.Alert.Term)
+	assert.Equal(t, "gonews released", received.Item.Title)
+}
+
+func TestWebhookReturnsErrorOnFailureStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewWebhook(server.URL, server.Client()).Notify(&feed.Alert{}, &feed.Item{})
+	assert.Error(t, err)
+}
+
+type failingNotifier struct {
+	calls int
+}
+
+func (n *failingNotifier) Notify(alert *feed.Alert, item *feed.Item) error {
+	n.calls++
+	return errors.New("failed")
+}
+
+func TestMultiNotifierTriesEveryNotifier(t *testing.T) {
+	first, second := &failingNotifier{}, &failingNotifier{}
+
+	err := multiNotifier{first, second}.Notify(&feed.Alert{}, &feed.Item{})
+	assert.Error(t, err)
+	assert.Equal(t, 1, first.calls)
+	assert.Equal(t, 1, second.calls)
+}
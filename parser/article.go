@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Minimum length of the text of an extracted article; anything shorter is
+// more likely to be a teaser or navigation than the article body
+const minArticleLength = 250
+
+// ErrNoArticle is returned when no article body can be found in a page
+var ErrNoArticle = errors.New("no article found")
+
+var (
+	// Elements which never contain the article body
+	unlikelyElements = "script, style, noscript, iframe, form, nav, header, footer, aside, button, select, textarea, svg"
+
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text`)
+	negativeNames = regexp.MustCompile(`(?i)ad-|ads|banner|combx|comment|related|footer|menu|meta|nav|promo|share|sidebar|social|sponsor|widget`)
+)
+
+// Score the element by its class & id, which often name the article body
+func nameWeight(s *goquery.Selection) float64 {
+	var weight float64
+	for _, attr := range []string{"class", "id"} {
+		name := s.AttrOr(attr, "")
+		if name == "" {
+			continue
+		}
+
+		if negativeNames.MatchString(name) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(name) {
+			weight += 25
+		}
+	}
+
+	return weight
+}
+
+// Return the fraction of the element's text which is inside links
+func linkDensity(s *goquery.Selection) float64 {
+	textLength := len(strings.TrimSpace(s.Text()))
+	if textLength == 0 {
+		return 0
+	}
+
+	var linkLength int
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += len(strings.TrimSpace(a.Text()))
+	})
+
+	return float64(linkLength) / float64(textLength)
+}
+
+// Extract the main article body from the given HTML page, returning its HTML
+// Paragraphs are scored by their length & number of commas, and their scores
+// are added to their parent and, halved, to their grandparent; the container
+// with the highest score, discounted by how much of it is links, is chosen
+func extractArticle(page []byte) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	doc.Find(unlikelyElements).Remove()
+
+	// Scores are keyed by node, since each lookup creates a new selection
+	scores := make(map[interface{}]float64)
+	var candidates []*goquery.Selection
+
+	addScore := func(s *goquery.Selection, score float64) {
+		if s.Length() == 0 {
+			return
+		}
+
+		node := s.Get(0)
+		if _, exists := scores[node]; !exists {
+			scores[node] = nameWeight(s)
+			candidates = append(candidates, s)
+		}
+
+		scores[node] += score
+	}
+
+	doc.Find("p, pre, td").Each(func(_ int, p *goquery.Selection) {
+		text := strings.TrimSpace(p.Text())
+		if len(text) < 25 {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+		addScore(p.Parent(), score)
+		addScore(p.Parent().Parent(), score/2)
+	})
+
+	var top *goquery.Selection
+	var topScore float64
+	for _, c := range candidates {
+		score := scores[c.Get(0)] * (1 - linkDensity(c))
+		if top == nil || score > topScore {
+			top = c
+			topScore = score
+		}
+	}
+
+	if top == nil || len(strings.TrimSpace(top.Text())) < minArticleLength {
+		return "", ErrNoArticle
+	}
+
+	article, err := top.Html()
+	if err != nil {
+		return "", fmt.Errorf("failed to render article: %w", err)
+	}
+
+	return strings.TrimSpace(article), nil
+}
+
+// ParseArticle fetches the page at the given link and extracts the body of
+// the article from it
+func (p *gfParser) ParseArticle(ctx context.Context, link string) (string, error) {
+	_, body, err := p.client.fetch(ctx, link)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch article: %w", err)
+	}
+
+	return extractArticle(body)
+}
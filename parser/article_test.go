@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockArticlePage = `<!DOCTYPE html>
+<html>
+<head><title>Article</title><script>var tracking = true;</script></head>
+<body>
+  <nav><a href="/">Home</a> <a href="/news">News</a> <a href="/about">About</a></nav>
+  <div class="sidebar">
+    <p><a href="/popular/1">The most popular story of the week, which everyone is reading</a></p>
+    <p><a href="/popular/2">Another popular story, with a long headline to make it count</a></p>
+  </div>
+  <div class="post-content">
+    <h1>Patch now</h1>
+    <p>Researchers disclosed a critical vulnerability in a widely used library on Tuesday, urging administrators to patch their servers, workstations, and appliances as soon as possible.</p>
+    <p>The flaw, which affects all versions released in the last three years, allows a remote attacker to execute arbitrary code without authentication, according to the advisory.</p>
+    <p>Vendors have started shipping fixes, and the maintainers said that they had seen no evidence of exploitation in the wild, though that is expected to change quickly.</p>
+  </div>
+  <div class="comments">
+    <p>Great article, thanks for sharing!</p>
+  </div>
+  <footer><p>Copyright, all rights reserved, no reproduction without permission.</p></footer>
+</body>
+</html>`
+
+const mockTeaserPage = `<!DOCTYPE html>
+<html>
+<body>
+  <nav><a href="/">Home</a></nav>
+  <div class="content"><p>Subscribe to read the rest of this article.</p></div>
+</body>
+</html>`
+
+func TestParseArticleExtractsArticleBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mockArticlePage))
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	article, err := p.ParseArticle(context.Background(), server.URL+"/article")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(article, "<h1>Patch now</h1>"))
+	assert.Contains(t, article, "Researchers disclosed a critical vulnerability")
+	assert.Contains(t, article, "no evidence of exploitation in the wild")
+	assert.NotContains(t, article, "popular story")
+	assert.NotContains(t, article, "Great article")
+	assert.NotContains(t, article, "Copyright")
+	assert.NotContains(t, article, "tracking")
+}
+
+func TestParseArticleReturnsErrorForTeaser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mockTeaserPage))
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	_, err = p.ParseArticle(context.Background(), server.URL+"/article")
+	assert.True(t, errors.Is(err, ErrNoArticle))
+}
+
+func TestParseArticleReturnsErrorForUnsuccessfulStatus(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	_, err = p.ParseArticle(context.Background(), server.URL+"/article")
+	var httpErr HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+}
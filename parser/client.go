@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gonews/config"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	userAgent = "gonews/1.0"
+
+	// Timeout of requests for feeds which don't configure one
+	defaultTimeout = 30 * time.Second
+)
+
+// ErrBodyTooLarge is returned when a response is larger than the maximum
+// configured for the feed
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// ErrUnsupportedScheme is returned when a URL which must be fetched from a
+// public address isn't http(s)
+var ErrUnsupportedScheme = errors.New("unsupported url scheme")
+
+// ErrNonPublicAddress is returned when a URL which must be fetched from a
+// public address resolves to, or redirects to, a loopback, private or
+// link-local address
+var ErrNonPublicAddress = errors.New("non-public address")
+
+// Ranges of private addresses, which net.IP.IsGlobalUnicast doesn't exclude
+var privateNets = parseCIDRs(
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",
+	"fc00::/7",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+
+	return nets
+}
+
+// client fetches a feed, with the options from its config
+type client struct {
+	http         *http.Client
+	userAgent    string
+	headers      map[string]string
+	credentials  *config.Credentials
+	maxBodyBytes int64
+	command      []string
+	timeout      time.Duration
+	scrape       *config.ScrapeConfig
+	maildir      string
+}
+
+// Create a client with the options from the given feed config, or the default
+// options if nil
+func newClient(fc *config.FeedConfig) *client {
+	c := &client{
+		http:      &http.Client{Timeout: defaultTimeout},
+		userAgent: userAgent,
+		timeout:   defaultTimeout,
+	}
+
+	if fc == nil {
+		return c
+	}
+
+	if fc.Timeout > 0 {
+		c.http.Timeout = fc.Timeout
+		c.timeout = fc.Timeout
+	}
+	if fc.UserAgent != "" {
+		c.userAgent = fc.UserAgent
+	}
+	c.headers = fc.Headers
+	c.credentials = fc.Credentials
+	c.maxBodyBytes = fc.MaxBodyBytes
+	c.command = fc.Command
+	c.scrape = fc.Scrape
+	c.maildir = fc.Maildir
+
+	return c
+}
+
+// Create a client with the default options, which only connects to public
+// addresses
+// Addresses are checked once they're resolved, when dialing, so that neither
+// redirects nor DNS records can point the client at the server's network, and
+// proxies are bypassed, since their address would be checked instead
+func newPublicClient() *client {
+	c := newClient(nil)
+	dialer := &net.Dialer{
+		Timeout: defaultTimeout,
+		Control: checkPublicAddress,
+	}
+	c.http.Transport = &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	return c
+}
+
+// Return an error unless the given resolved address is public
+func checkPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse address: %w", err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+
+	return nil
+}
+
+// Return true unless the IP is a loopback, private, link-local, multicast or
+// unspecified address
+func isPublicIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() {
+		return false
+	}
+
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Create a GET request for the URL with the client's headers & credentials,
+// which is cancelled when the context is done
+func (c *client) newRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	if c.credentials != nil {
+		req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+	}
+
+	return req, nil
+}
+
+// Read the response body, failing if it's larger than the client's maximum,
+// if any
+func (c *client) readBody(body io.Reader) ([]byte, error) {
+	if c.maxBodyBytes <= 0 {
+		return ioutil.ReadAll(body)
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(body, c.maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(b)) > c.maxBodyBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, c.maxBodyBytes)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"gonews/config"
+	"gonews/feed"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFeedSendsConfiguredOptions(t *testing.T) {
+	body, err := ioutil.ReadFile("../lib/test/sample.xml")
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		assert.Equal(t, "session=abc", r.Header.Get("Cookie"))
+		assert.Equal(t, "custom-agent", r.Header.Get("User-Agent"))
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	p, err := New(&config.Config{
+		Feeds: []*config.FeedConfig{
+			{
+				URL:         server.URL + "/private",
+				Headers:     map[string]string{"cookie": "session=abc"},
+				UserAgent:   "custom-agent",
+				BasicAuth:   "private",
+				Credentials: &config.Credentials{Username: "user", Password: "secret"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	resp, err := p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL + "/private"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.Items)
+
+	// Other feeds are fetched without the options
+	_, err = p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL + "/public"})
+	var httpErr HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusUnauthorized, httpErr.StatusCode)
+}
+
+func TestParseFeedReturnsErrorWhenBodyTooLarge(t *testing.T) {
+	server := mockFeedServer(t)
+	defer server.Close()
+
+	p, err := New(&config.Config{
+		Feeds: []*config.FeedConfig{{URL: server.URL, MaxBodyBytes: 16}},
+	})
+	assert.NoError(t, err)
+
+	_, err = p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL})
+	assert.True(t, errors.Is(err, ErrBodyTooLarge))
+}
+
+func TestParseFeedTimesOut(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	p, err := New(&config.Config{
+		Feeds: []*config.FeedConfig{{URL: server.URL, Timeout: 10 * time.Millisecond}},
+	})
+	assert.NoError(t, err)
+
+	_, err = p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL})
+	assert.Error(t, err)
+}
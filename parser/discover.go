@@ -0,0 +1,233 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+// Paths which commonly serve a site's feed, probed when a page doesn't
+// advertise any
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss",
+	"/feed.xml",
+	"/rss.xml",
+	"/atom.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+// Media types of the alternate links which point to feeds
+var feedMediaTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// Candidate is a feed found by Discover
+type Candidate struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+func (c Candidate) String() string {
+	return fmt.Sprintf(
+		"Candidate{URL: %s, Title: %s, Type: %s}",
+		c.URL,
+		c.Title,
+		c.Type)
+}
+
+func feedMediaType(feedType gofeed.FeedType) string {
+	switch feedType {
+	case gofeed.FeedTypeRSS:
+		return "application/rss+xml"
+	case gofeed.FeedTypeAtom:
+		return "application/atom+xml"
+	case gofeed.FeedTypeJSON:
+		return "application/feed+json"
+	}
+
+	return ""
+}
+
+// Fetch the body of the given URL, returning the final URL after any
+// redirects
+func (c *client) fetch(ctx context.Context, rawURL string) (*url.URL, []byte, error) {
+	req, err := c.newRequest(ctx, rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+	}
+
+	body, err := c.readBody(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return resp.Request.URL, body, nil
+}
+
+// Return the given document as a candidate if it's a feed, or nil otherwise
+func (p *gfParser) feedCandidate(u *url.URL, body []byte) *Candidate {
+	feedType := gofeed.DetectFeedType(bytes.NewReader(body))
+	if isJSONFeed("", body) {
+		feedType = gofeed.FeedTypeJSON
+	}
+
+	if feedType == gofeed.FeedTypeUnknown {
+		return nil
+	}
+
+	gfeed, err := p.parseGofeed("", body)
+	if err != nil {
+		return nil
+	}
+
+	return &Candidate{
+		URL:   u.String(),
+		Title: gfeed.Title,
+		Type:  feedMediaType(feedType),
+	}
+}
+
+// Find the feeds advertised by the alternate links of the given HTML page
+func linkCandidates(u *url.URL, body []byte) ([]*Candidate, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	base := u
+	if href, exists := doc.Find("base[href]").First().Attr("href"); exists {
+		baseURL, err := u.Parse(href)
+		if err == nil {
+			base = baseURL
+		}
+	}
+
+	var candidates []*Candidate
+	seen := make(map[string]bool)
+	doc.Find("link[rel][href]").Each(func(_ int, s *goquery.Selection) {
+		rel := strings.Fields(strings.ToLower(s.AttrOr("rel", "")))
+		mediaType := strings.ToLower(strings.TrimSpace(s.AttrOr("type", "")))
+		if !contains(rel, "alternate") || !feedMediaTypes[mediaType] {
+			return
+		}
+
+		feedURL, err := base.Parse(strings.TrimSpace(s.AttrOr("href", "")))
+		if err != nil || seen[feedURL.String()] {
+			return
+		}
+		seen[feedURL.String()] = true
+
+		candidates = append(candidates, &Candidate{
+			URL:   feedURL.String(),
+			Title: strings.TrimSpace(s.AttrOr("title", "")),
+			Type:  mediaType,
+		})
+	})
+
+	return candidates, nil
+}
+
+func contains(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Discover finds the feeds available from the given website URL
+// If the URL is itself a feed, it's the only candidate; otherwise the feeds
+// advertised by the page's alternate links are returned, falling back to any
+// common feed paths on the same site which serve a feed
+func (p *gfParser) Discover(ctx context.Context, pageURL string) ([]*Candidate, error) {
+	return p.discover(ctx, p.client, pageURL)
+}
+
+// DiscoverPublic finds the feeds available from the given website URL, like
+// Discover, but only fetches http(s) URLs from public addresses, so that
+// users of the server can't make it probe its own network
+func (p *gfParser) DiscoverPublic(ctx context.Context, pageURL string) ([]*Candidate, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, u.Scheme)
+	}
+
+	return p.discover(ctx, p.publicClient, pageURL)
+}
+
+// Find the feeds available from the given website URL, fetching its pages
+// with the given client
+func (p *gfParser) discover(ctx context.Context, c *client, pageURL string) ([]*Candidate, error) {
+	u, body, err := c.fetch(ctx, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+
+	if c := p.feedCandidate(u, body); c != nil {
+		return []*Candidate{c}, nil
+	}
+
+	candidates, err := linkCandidates(u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	// Several paths may redirect to the same feed
+	seen := make(map[string]bool)
+	for _, feedPath := range commonFeedPaths {
+		feedURL := &url.URL{
+			Scheme: u.Scheme,
+			Host:   u.Host,
+			Path:   feedPath,
+		}
+
+		// Most sites won't serve most of the paths, so failures are
+		// expected
+		finalURL, body, err := c.fetch(ctx, feedURL.String())
+		if err != nil {
+			continue
+		}
+
+		c := p.feedCandidate(finalURL, body)
+		if c != nil && !seen[c.URL] {
+			seen[c.URL] = true
+			candidates = append(candidates, c)
+		}
+	}
+
+	return candidates, nil
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Test</title>
+  <link rel="stylesheet" href="/style.css">
+  <link rel="alternate" type="application/rss+xml" title="RSS" href="/rss.xml">
+  <link rel="alternate" type="application/atom+xml" title="Atom" href="https://example.com/atom.xml">
+  <link rel="alternate" type="application/rss+xml" title="Duplicate" href="/rss.xml">
+  <link rel="alternate" type="text/html" hreflang="fr" href="/fr">
+</head>
+<body></body>
+</html>`
+
+func TestDiscoverReturnsAlternateLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mockPage))
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	candidates, err := p.Discover(context.Background(), server.URL+"/blog/")
+	assert.NoError(t, err)
+	assert.Equal(t, []*Candidate{
+		{
+			URL:   server.URL + "/rss.xml",
+			Title: "RSS",
+			Type:  "application/rss+xml",
+		},
+		{
+			URL:   "https://example.com/atom.xml",
+			Title: "Atom",
+			Type:  "application/atom+xml",
+		},
+	}, candidates)
+}
+
+func TestDiscoverProbesCommonPaths(t *testing.T) {
+	body, err := ioutil.ReadFile("../lib/test/sample.xml")
+	assert.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte("<html><head><title>No feeds</title></head></html>"))
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feed", http.StatusMovedPermanently)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	candidates, err := p.Discover(context.Background(), server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, server.URL+"/feed", candidates[0].URL)
+	assert.Equal(t, "application/rss+xml", candidates[0].Type)
+	assert.NotEmpty(t, candidates[0].Title)
+}
+
+func TestDiscoverReturnsFeedURL(t *testing.T) {
+	server := mockFeedServer(t)
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	candidates, err := p.Discover(context.Background(), server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, server.URL, candidates[0].URL)
+}
+
+func TestDiscoverPublicRejectsUnsupportedSchemes(t *testing.T) {
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	for _, pageURL := range []string{"file:///etc/passwd", "ftp://example.com/", "example.com"} {
+		_, err = p.DiscoverPublic(context.Background(), pageURL)
+		assert.True(t, errors.Is(err, ErrUnsupportedScheme), pageURL)
+	}
+}
+
+func TestDiscoverPublicRejectsLoopbackAddresses(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Write([]byte(mockPage))
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	_, err = p.DiscoverPublic(context.Background(), server.URL)
+	assert.True(t, errors.Is(err, ErrNonPublicAddress))
+	assert.False(t, requested)
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"100.64.0.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.public, isPublicIP(net.ParseIP(test.ip)), test.ip)
+	}
+}
@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gonews/feed"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Maximum size of an icon, since they're stored & served by the app
+const maxIconBytes = 1 << 20
+
+// Content types of the icons which are accepted; only raster formats are, since
+// the icons are served from the app's origin, and SVGs can contain scripts
+var iconContentTypes = map[string]bool{
+	"image/gif":    true,
+	"image/jpeg":   true,
+	"image/png":    true,
+	"image/webp":   true,
+	"image/x-icon": true,
+}
+
+// ErrIconNotFound is returned when none of the icon sources has an icon
+var ErrIconNotFound = errors.New("icon not found")
+
+// Icon contains an icon of a feed, and the URL it was fetched from
+type Icon struct {
+	URL         string
+	ContentType string
+	Data        []byte
+}
+
+// Fetch the image at the URL, failing if the response isn't an image
+func (p *gfParser) fetchImage(ctx context.Context, rawURL string) (*Icon, error) {
+	req, err := p.client.newRequest(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxIconBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(data) > maxIconBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, maxIconBytes)
+	}
+
+	// Servers often send icons with a generic content type, and the one
+	// they send can't be trusted, so it's sniffed instead
+	contentType := http.DetectContentType(data)
+	if !iconContentTypes[contentType] {
+		return nil, fmt.Errorf("not a supported image: %s", contentType)
+	}
+
+	return &Icon{
+		URL:         resp.Request.URL.String(),
+		ContentType: contentType,
+		Data:        data,
+	}, nil
+}
+
+// Return the URLs of the icons linked from the page, in order
+func linkedIcons(u *url.URL, body []byte) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	base := u
+	if href, exists := doc.Find("base[href]").First().Attr("href"); exists {
+		baseURL, err := u.Parse(href)
+		if err == nil {
+			base = baseURL
+		}
+	}
+
+	var icons []string
+	doc.Find("link[rel][href]").Each(func(_ int, s *goquery.Selection) {
+		rel := strings.Fields(strings.ToLower(s.AttrOr("rel", "")))
+		if !contains(rel, "icon") && !contains(rel, "apple-touch-icon") {
+			return
+		}
+
+		iconURL, err := base.Parse(strings.TrimSpace(s.AttrOr("href", "")))
+		if err == nil {
+			icons = append(icons, iconURL.String())
+		}
+	})
+
+	return icons, nil
+}
+
+// Return the URL of the feed's website: its link if it has one, or else the
+// root of its URL's host
+func siteURL(f *feed.Feed) (*url.URL, error) {
+	// Stored links are escaped; see feed.MetadataFromGofeed
+	if f.Link != "" {
+		u, err := url.Parse(html.UnescapeString(f.Link))
+		if err == nil && u.IsAbs() {
+			return u, nil
+		}
+	}
+
+	u, err := url.Parse(f.URL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("feed has no website: %s", f.URL)
+	}
+
+	return &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}, nil
+}
+
+// FetchIcon fetches the feed's icon: the feed's image if it has one, or else
+// its website's /favicon.ico, or else the icon linked from the website's page
+func (p *gfParser) FetchIcon(ctx context.Context, f *feed.Feed) (*Icon, error) {
+	if f.Image != "" {
+		icon, err := p.fetchImage(ctx, html.UnescapeString(f.Image))
+		if err == nil {
+			return icon, nil
+		}
+	}
+
+	site, err := siteURL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrIconNotFound, err)
+	}
+
+	favicon := &url.URL{Scheme: site.Scheme, Host: site.Host, Path: "/favicon.ico"}
+	icon, err := p.fetchImage(ctx, favicon.String())
+	if err == nil {
+		return icon, nil
+	}
+
+	u, body, err := p.client.fetch(ctx, site.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to fetch website: %s", ErrIconNotFound, err)
+	}
+
+	iconURLs, err := linkedIcons(u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iconURL := range iconURLs {
+		icon, err := p.fetchImage(ctx, iconURL)
+		if err == nil {
+			return icon, nil
+		}
+	}
+
+	return nil, ErrIconNotFound
+}
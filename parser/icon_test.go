@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"gonews/feed"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	mockPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+	mockICO = []byte("\x00\x00\x01\x00\x01\x00\x10\x10")
+)
+
+func TestFetchIconPrefersFeedImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/logo.png":
+			w.Write(mockPNG)
+		case "/favicon.ico":
+			w.Write(mockICO)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	icon, err := p.FetchIcon(context.Background(), &feed.Feed{URL: server.URL + "/feed.xml", Image: server.URL + "/logo.png"})
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/logo.png", icon.URL)
+	assert.Equal(t, "image/png", icon.ContentType)
+	assert.Equal(t, mockPNG, icon.Data)
+}
+
+func TestFetchIconFallsBackToFavicon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/logo.png":
+			// Not an image
+			w.Write([]byte("<html></html>"))
+		case "/favicon.ico":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(mockICO)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	icon, err := p.FetchIcon(context.Background(), &feed.Feed{
+		URL:   server.URL + "/feed.xml",
+		Link:  server.URL + "/blog/",
+		Image: server.URL + "/logo.png",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/favicon.ico", icon.URL)
+	assert.Equal(t, "image/x-icon", icon.ContentType)
+}
+
+func TestFetchIconRejectsSVG(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/logo.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+		case "/favicon.ico":
+			w.Write(mockICO)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	icon, err := p.FetchIcon(context.Background(), &feed.Feed{URL: server.URL + "/feed.xml", Image: server.URL + "/logo.svg"})
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/favicon.ico", icon.URL)
+	assert.Equal(t, "image/x-icon", icon.ContentType)
+}
+
+func TestFetchIconFallsBackToLinkedIcon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blog/":
+			w.Write([]byte(`<html><head>
+<link rel="stylesheet" href="style.css">
+<link rel="shortcut icon" href="static/icon.png">
+</head></html>`))
+		case "/blog/static/icon.png":
+			w.Write(mockPNG)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	icon, err := p.FetchIcon(context.Background(), &feed.Feed{URL: server.URL + "/feed.xml", Link: server.URL + "/blog/"})
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/blog/static/icon.png", icon.URL)
+}
+
+func TestFetchIconReturnsErrorWhenNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte("<html></html>"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	_, err = p.FetchIcon(context.Background(), &feed.Feed{URL: server.URL + "/feed.xml"})
+	assert.True(t, errors.Is(err, ErrIconNotFound))
+
+	_, err = p.FetchIcon(context.Background(), &feed.Feed{URL: "command:/usr/local/bin/feed"})
+	assert.True(t, errors.Is(err, ErrIconNotFound))
+}
@@ -0,0 +1,257 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Maximum length of the title derived from the content of an untitled item
+const maxDerivedTitleLength = 80
+
+// JSON Feed 1.0/1.1 document; see https://www.jsonfeed.org/version/1.1/
+// Only the fields which map onto items or links are decoded
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Icon        string           `json:"icon"`
+	Language    string           `json:"language"` // 1.1
+	Author      *jsonFeedAuthor  `json:"author"`   // 1.0
+	Authors     []jsonFeedAuthor `json:"authors"`  // 1.1
+	Hubs        []jsonFeedHub    `json:"hubs"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type jsonFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type jsonFeedItem struct {
+	ID            json.RawMessage      `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Image         string               `json:"image"`
+	Tags          []string             `json:"tags"`
+	Attachments   []jsonFeedAttachment `json:"attachments"`
+	Author        *jsonFeedAuthor      `json:"author"`  // 1.0
+	Authors       []jsonFeedAuthor     `json:"authors"` // 1.1
+}
+
+type jsonFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+}
+
+// Return true if the document is a JSON Feed, either according to its content
+// type or by sniffing its contents; servers commonly label JSON Feeds as
+// application/json or text/plain
+func isJSONFeed(contentType string, body []byte) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && mediaType == "application/feed+json" {
+		return true
+	}
+
+	// The version URL may have its slashes escaped
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) &&
+		bytes.Contains(body, []byte("jsonfeed.org"))
+}
+
+func parseJSONFeed(body []byte) (*jsonFeed, error) {
+	var jf jsonFeed
+	err := json.Unmarshal(body, &jf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse json feed: %w", err)
+	}
+
+	// Minor versions are backwards compatible
+	if !strings.HasPrefix(jf.Version, "https://jsonfeed.org/version/1") {
+		return nil, fmt.Errorf("failed to parse json feed: unsupported version: %s", jf.Version)
+	}
+
+	return &jf, nil
+}
+
+// Return the first author, preferring the 1.1 authors list
+func firstAuthor(author *jsonFeedAuthor, authors []jsonFeedAuthor) *jsonFeedAuthor {
+	if len(authors) > 0 {
+		return &authors[0]
+	}
+
+	return author
+}
+
+// Convert the author to a gofeed person; JSON Feed authors have no email, but
+// a mailto URL is used as one
+func (a *jsonFeedAuthor) toGofeed() *gofeed.Person {
+	if a == nil {
+		return nil
+	}
+
+	var email string
+	if strings.HasPrefix(a.URL, "mailto:") {
+		email = strings.TrimPrefix(a.URL, "mailto:")
+	}
+
+	return &gofeed.Person{
+		Name:  a.Name,
+		Email: email,
+	}
+}
+
+func parseJSONFeedTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+
+	return &t
+}
+
+// IDs are meant to be strings, but some feeds use numbers
+func (item *jsonFeedItem) guid() string {
+	var id string
+	err := json.Unmarshal(item.ID, &id)
+	if err != nil {
+		return strings.TrimSpace(string(item.ID))
+	}
+
+	return id
+}
+
+// Convert the item to a gofeed item, so that it's mapped onto feed items by
+// the same rules as RSS & Atom items
+// Items without an author inherit the feed's authors
+func (jf *jsonFeed) itemToGofeed(item *jsonFeedItem) *gofeed.Item {
+	authors := item.Authors
+	author := firstAuthor(item.Author, item.Authors)
+	if author == nil {
+		authors = jf.Authors
+		author = firstAuthor(jf.Author, jf.Authors)
+	}
+
+	var names []string
+	for _, a := range authors {
+		names = append(names, a.Name)
+	}
+
+	// Summaries & text content are plain text, while descriptions are HTML
+	description := html.EscapeString(item.Summary)
+	if description == "" {
+		description = html.EscapeString(item.ContentText)
+	}
+	if description == "" {
+		description = item.ContentHTML
+	}
+
+	// Titles are optional, ex. for microblog posts
+	title := item.Title
+	if title == "" {
+		runes := []rune(strings.TrimSpace(item.ContentText))
+		if len(runes) > maxDerivedTitleLength {
+			runes = append(runes[:maxDerivedTitleLength], []rune("...")...)
+		}
+		title = string(runes)
+	}
+
+	link := item.URL
+	if link == "" {
+		link = item.ExternalURL
+	}
+
+	published := parseJSONFeedTime(item.DatePublished)
+	if published == nil {
+		published = parseJSONFeedTime(item.DateModified)
+	}
+
+	var image *gofeed.Image
+	if item.Image != "" {
+		image = &gofeed.Image{URL: item.Image}
+	}
+
+	var enclosures []*gofeed.Enclosure
+	for _, a := range item.Attachments {
+		var length string
+		if a.SizeInBytes > 0 {
+			length = strconv.FormatInt(a.SizeInBytes, 10)
+		}
+
+		enclosures = append(enclosures, &gofeed.Enclosure{
+			URL:    a.URL,
+			Length: length,
+			Type:   a.MimeType,
+		})
+	}
+
+	return &gofeed.Item{
+		GUID:            item.guid(),
+		Title:           title,
+		Description:     description,
+		Content:         item.ContentHTML,
+		Link:            link,
+		Author:          author.toGofeed(),
+		DublinCoreExt:   creators(names),
+		Published:       item.DatePublished,
+		PublishedParsed: published,
+		Updated:         item.DateModified,
+		UpdatedParsed:   parseJSONFeedTime(item.DateModified),
+		Image:           image,
+		Categories:      item.Tags,
+		Enclosures:      enclosures,
+	}
+}
+
+func (jf *jsonFeed) toGofeed() *gofeed.Feed {
+	gfeed := &gofeed.Feed{
+		Title:       jf.Title,
+		Description: jf.Description,
+		Link:        jf.HomePageURL,
+		FeedLink:    jf.FeedURL,
+		Language:    jf.Language,
+		FeedType:    "json",
+	}
+	if jf.Icon != "" {
+		gfeed.Image = &gofeed.Image{URL: jf.Icon}
+	}
+
+	for i := range jf.Items {
+		gfeed.Items = append(gfeed.Items, jf.itemToGofeed(&jf.Items[i]))
+	}
+
+	return gfeed
+}
+
+// Set the WebSub links from the feed's hubs & feed URL
+func (jf *jsonFeed) links(links *Links) {
+	for _, hub := range jf.Hubs {
+		if strings.EqualFold(hub.Type, "websub") {
+			links.set("hub", hub.URL)
+		}
+	}
+
+	if jf.FeedURL != "" {
+		links.set("self", jf.FeedURL)
+	}
+}
@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"context"
+	"gonews/feed"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockJSONFeed = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Test",
+  "home_page_url": "https://example.com/",
+  "feed_url": "https://example.com/feed.json",
+  "description": "Test posts",
+  "icon": "https://example.com/icon.png",
+  "language": "en",
+  "authors": [{"name": "Feed Author"}],
+  "hubs": [{"type": "WebSub", "url": "https://hub.example.com/"}],
+  "items": [
+    {
+      "id": "1",
+      "url": "https://example.com/1",
+      "title": "<b>First</b>",
+      "summary": "First & foremost",
+      "content_html": "<p>First</p>",
+      "date_published": "2020-10-17T12:00:00Z",
+      "image": "https://example.com/1.png",
+      "tags": ["security", " "],
+      "attachments": [{"url": "https://example.com/1.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 1024}],
+      "authors": [{"name": "Item Author", "url": "mailto:author@example.com"}]
+    },
+    {
+      "id": "2",
+      "external_url": "https://example.com/2",
+      "content_text": "An untitled post",
+      "date_modified": "2020-10-18T12:00:00Z"
+    }
+  ]
+}`
+
+const mockJSONFeed10 = `{
+  "version": "https://jsonfeed.org/version/1",
+  "title": "Test",
+  "author": {"name": "Feed Author"},
+  "items": [
+    {
+      "id": 1,
+      "url": "https://example.com/1",
+      "title": "First",
+      "content_text": "First",
+      "author": {"name": "Item Author"}
+    }
+  ]
+}`
+
+func TestParseJSONFeed(t *testing.T) {
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	items, err := p.Parse(strings.NewReader(mockJSONFeed), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []*feed.Item{
+		{
+			Name:        "Item Author",
+			Email:       "author@example.com",
+			Title:       "&lt;b&gt;First&lt;/b&gt;",
+			Description: "First &amp; foremost",
+			Link:        "https://example.com/1",
+			GUID:        "1",
+			Hash:        feed.ContentHash("<b>First</b>", "First &amp; foremost"),
+			Published:   time.Date(2020, time.October, 17, 12, 0, 0, 0, time.UTC),
+			Content:     "<p>First</p>",
+			Image:       "https://example.com/1.png",
+			Categories:  []*feed.ItemCategory{{Name: "security"}},
+			Enclosures: []*feed.Enclosure{
+				{
+					URL:    "https://example.com/1.mp3",
+					Length: 1024,
+					Type:   "audio/mpeg",
+				},
+			},
+		},
+		{
+			Name:        "Feed Author",
+			Title:       "An untitled post",
+			Description: "An untitled post",
+			Link:        "https://example.com/2",
+			GUID:        "2",
+			Hash:        feed.ContentHash("An untitled post", "An untitled post"),
+			Published:   time.Date(2020, time.October, 18, 12, 0, 0, 0, time.UTC),
+			Updated:     time.Date(2020, time.October, 18, 12, 0, 0, 0, time.UTC),
+		},
+	}, items)
+}
+
+func TestParseJSONFeed10(t *testing.T) {
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	items, err := p.Parse(strings.NewReader(mockJSONFeed10), "")
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "Item Author", items[0].Name)
+	assert.Equal(t, "First", items[0].Title)
+	assert.Equal(t, "1", items[0].GUID)
+}
+
+func TestParseJSONFeedKeepsExtraAuthors(t *testing.T) {
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	items, err := p.Parse(strings.NewReader(`{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Test",
+  "authors": [{"name": "Feed Author"}, {"name": "Feed Editor"}],
+  "items": [
+    {"id": "1", "title": "First", "authors": [{"name": "Alice"}, {"name": "Bob"}]},
+    {"id": "2", "title": "Second"}
+  ]
+}`), "")
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "Alice", items[0].Name)
+	assert.Equal(t, []*feed.ItemAuthor{{Name: "Bob"}}, items[0].Authors)
+	assert.Equal(t, "Feed Author", items[1].Name)
+	assert.Equal(t, []*feed.ItemAuthor{{Name: "Feed Editor"}}, items[1].Authors)
+}
+
+func TestParseJSONFeedReturnsErrorOnUnsupportedVersion(t *testing.T) {
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	_, err = p.Parse(strings.NewReader(`{"version": "https://jsonfeed.org/version/0", "items": []}`), "")
+	assert.Error(t, err)
+}
+
+func TestParseFeedDetectsJSONFeedByContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+		w.Write([]byte(mockJSONFeed))
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	resp, err := p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 2)
+	assert.Equal(t, Links{
+		Hub:  "https://hub.example.com/",
+		Self: "https://example.com/feed.json",
+	}, resp.Links)
+	assert.Equal(t, &feed.Metadata{
+		Title:       "Test",
+		Link:        "https://example.com/",
+		Description: "Test posts",
+		Image:       "https://example.com/icon.png",
+		Language:    "en",
+	}, resp.Metadata)
+}
+
+func TestIsJSONFeed(t *testing.T) {
+	assert.True(t, isJSONFeed("application/feed+json", nil))
+	assert.True(t, isJSONFeed("application/json", []byte(mockJSONFeed)))
+	assert.True(t, isJSONFeed("text/plain", []byte(`{"version":"https:\/\/jsonfeed.org\/version\/1"}`)))
+	assert.False(t, isJSONFeed("application/json", []byte(`{"version":"1"}`)))
+	assert.False(t, isJSONFeed("application/rss+xml", []byte(`<rss></rss>`)))
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Links contains the WebSub discovery links advertised by a feed
+// Hub is the URL of the hub which the feed publishes to, and Self is the
+// canonical URL of the feed, used as the topic when subscribing
+type Links struct {
+	Hub  string
+	Self string
+}
+
+func (l *Links) set(rel, href string) {
+	switch strings.ToLower(rel) {
+	case "hub":
+		if l.Hub == "" {
+			l.Hub = href
+		}
+	case "self":
+		if l.Self == "" {
+			l.Self = href
+		}
+	}
+}
+
+// Parse links from Link headers, ex. '<https://hub.example.com/>; rel="hub"'
+func headerLinks(header http.Header, links *Links) {
+	for _, value := range header["Link"] {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+
+			href := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(href, "<") || !strings.HasSuffix(href, ">") {
+				continue
+			}
+			href = strings.Trim(href, "<>")
+
+			for _, param := range parts[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) != 2 || strings.ToLower(kv[0]) != "rel" {
+					continue
+				}
+
+				for _, rel := range strings.Fields(strings.Trim(kv[1], `"`)) {
+					links.set(rel, href)
+				}
+			}
+		}
+	}
+}
+
+// Parse links from the <link rel="..." href="..."> elements of an Atom feed,
+// or the <atom:link> elements of an RSS feed
+func bodyLinks(body []byte, links *Links) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// Only the link attributes are needed, which are expected to be
+		// ASCII
+		return input, nil
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return
+		}
+
+		elem, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		// Links in the channel/feed header come before any entries
+		if elem.Name.Local == "item" || elem.Name.Local == "entry" {
+			return
+		}
+
+		if elem.Name.Local != "link" {
+			continue
+		}
+
+		var rel, href string
+		for _, attr := range elem.Attr {
+			switch attr.Name.Local {
+			case "rel":
+				rel = attr.Value
+			case "href":
+				href = attr.Value
+			}
+		}
+
+		if href != "" {
+			links.set(rel, href)
+		}
+	}
+}
+
+// Find the WebSub hub & self links advertised in the response headers or the
+// feed body; headers take precedence
+func findLinks(header http.Header, contentType string, body []byte) Links {
+	var links Links
+	headerLinks(header, &links)
+
+	if isJSONFeed(contentType, body) {
+		jf, err := parseJSONFeed(body)
+		if err == nil {
+			jf.links(&links)
+		}
+	} else {
+		bodyLinks(body, &links)
+	}
+
+	return links
+}
@@ -0,0 +1,319 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"gonews/feed"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html/charset"
+)
+
+// Decodes RFC 2047 encoded words in headers, ex. "=?ISO-8859-1?Q?caf=E9?="
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: charset.NewReaderLabel,
+}
+
+// Header of a message or message part
+type mimeHeader interface {
+	Get(string) string
+}
+
+// Return the part's body as UTF-8 text, decoding its transfer encoding &
+// charset
+// Quoted-printable parts of multipart messages are already decoded, and the
+// header removed, by the multipart reader
+func decodeText(header mimeHeader, params map[string]string, body io.Reader) (string, error) {
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body})
+	}
+
+	if cs := params["charset"]; cs != "" && !strings.EqualFold(cs, "utf-8") {
+		var err error
+		body, err = charset.NewReaderLabel(cs, body)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode charset: %w", err)
+		}
+	}
+
+	text, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read body: %w", err)
+	}
+
+	return string(text), nil
+}
+
+// newlineStripper removes the line breaks from base64 encoded bodies, which
+// the base64 decoder doesn't accept
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s *newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+
+	return kept, err
+}
+
+// Find the HTML & plain text bodies of the message part, descending into
+// multipart parts; attachments are ignored, and the first body of each type
+// is kept
+func messageBodies(header mimeHeader, body io.Reader, htmlBody, textBody *string) error {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("failed to parse content type: %w", err)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read message part: %w", err)
+			}
+
+			disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+			if disposition == "attachment" {
+				continue
+			}
+
+			err = messageBodies(part.Header, part, htmlBody, textBody)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var target *string
+	switch mediaType {
+	case "text/html":
+		target = htmlBody
+	case "text/plain":
+		target = textBody
+	default:
+		return nil
+	}
+
+	if *target != "" {
+		return nil
+	}
+
+	*target, err = decodeText(header, params, body)
+
+	return err
+}
+
+// Convert the plain text into HTML paragraphs
+func textToHTML(text string) string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+
+	var b strings.Builder
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+
+		lines := strings.Split(html.EscapeString(paragraph), "\n")
+		b.WriteString("<p>" + strings.Join(lines, "<br>") + "</p>")
+	}
+
+	return b.String()
+}
+
+// Parse the message into a gofeed item, with the subject as its title, the
+// sender as its author, and the HTML body, or else the plain text body, as its
+// content
+// The Message-ID is used as the GUID, falling back to the message's unique
+// name in the Maildir
+func parseMessage(name string, message []byte) (*gofeed.Item, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(message))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	subject, err := wordDecoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	var author *gofeed.Person
+	addressParser := &mail.AddressParser{WordDecoder: wordDecoder}
+	from, err := addressParser.Parse(msg.Header.Get("From"))
+	if err == nil {
+		author = &gofeed.Person{Name: from.Name, Email: from.Address}
+	}
+
+	guid := strings.Trim(strings.TrimSpace(msg.Header.Get("Message-ID")), "<>")
+	if guid == "" {
+		guid = name
+	}
+
+	var htmlBody, textBody string
+	err = messageBodies(msg.Header, msg.Body, &htmlBody, &textBody)
+	if err != nil {
+		return nil, err
+	}
+
+	content := htmlBody
+	if content == "" {
+		content = textToHTML(textBody)
+	}
+
+	item := &gofeed.Item{
+		GUID:    guid,
+		Title:   strings.TrimSpace(subject),
+		Content: content,
+		Author:  author,
+	}
+
+	date, err := msg.Header.Date()
+	if err == nil {
+		item.Published = msg.Header.Get("Date")
+		item.PublishedParsed = &date
+	}
+
+	return item, nil
+}
+
+// Return the unique name of the message file, without the info suffix
+func messageName(filename string) string {
+	return strings.SplitN(filename, ":", 2)[0]
+}
+
+// Move the message from new/ to cur/, with the given flags in its info suffix
+// Without flags, the suffix marks the message as seen by a reader, but not read
+func moveMessage(maildir, filename, flags string) error {
+	err := os.Rename(
+		filepath.Join(maildir, "new", filename),
+		filepath.Join(maildir, "cur", messageName(filename)+":2,"+flags))
+	if err != nil {
+		return fmt.Errorf("failed to move message: %w", err)
+	}
+
+	return nil
+}
+
+// Read the new messages from the feed's Maildir, newest first
+// The messages of the saved items are moved to cur/ when the response is
+// committed, so that they're only read once; if there are no new messages, the
+// feed is reported as not modified
+// Messages which can't be read are skipped, so that they don't hold up the rest
+// of the feed, and are also moved to cur/ when the response is committed,
+// flagged for the user's attention
+func (p *gfParser) parseMaildir(c *client) (*Response, error) {
+	newDir := filepath.Join(c.maildir, "new")
+	files, err := ioutil.ReadDir(newDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read maildir: %w", err)
+	}
+
+	gfeed := &gofeed.Feed{}
+	filenames := make(map[*gofeed.Item]string)
+	var unreadable []string
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		f, err := os.Open(filepath.Join(newDir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open message: %w", err)
+		}
+
+		message, err := c.readBody(f)
+		f.Close()
+		var item *gofeed.Item
+		if err == nil {
+			item, err = parseMessage(messageName(file.Name()), message)
+		}
+		if err != nil {
+			log.Warn().Err(err).Msgf("skipping unreadable message %s in %s", file.Name(), c.maildir)
+			unreadable = append(unreadable, file.Name())
+			continue
+		}
+
+		gfeed.Items = append(gfeed.Items, item)
+		filenames[item] = file.Name()
+	}
+
+	if len(filenames) == 0 && len(unreadable) == 0 {
+		return &Response{NotModified: true}, nil
+	}
+
+	sort.SliceStable(gfeed.Items, func(i, j int) bool {
+		a, b := gfeed.Items[i].PublishedParsed, gfeed.Items[j].PublishedParsed
+		return a != nil && (b == nil || a.After(*b))
+	})
+
+	// Relative links in the messages can't be resolved
+	items, err := itemsFromGofeed(gfeed, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Items are translated in order, so they're matched to their messages
+	// by position
+	itemFilenames := make(map[*feed.Item]string)
+	for i, item := range items {
+		itemFilenames[item] = filenames[gfeed.Items[i]]
+	}
+
+	return &Response{
+		Items: items,
+		Commit: func(saved []*feed.Item) error {
+			for _, filename := range unreadable {
+				err := moveMessage(c.maildir, filename, "F")
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, item := range saved {
+				filename, ok := itemFilenames[item]
+				if !ok {
+					continue
+				}
+
+				err := moveMessage(c.maildir, filename, "")
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,190 @@
+package parser
+
+import (
+	"context"
+	"gonews/config"
+	"gonews/feed"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockMultipartMessage = `From: "Security Weekly" <news@example.com>
+To: gonews@example.com
+Subject: Issue 42
+Date: Tue, 13 Oct 2026 09:00:00 +0000
+Message-ID: <issue-42@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="mixed"
+
+--mixed
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain; charset=utf-8
+
+Plain text version
+--alt
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+<p>This week&#39;s <a href=3D"https://example.com/42" onclick=3D"track()">iss=
+ue</a></p><script>track()</script>
+--alt--
+
+--mixed
+Content-Type: text/html
+Content-Disposition: attachment; filename="ad.html"
+
+<p>Attachment</p>
+--mixed--
+`
+
+const mockPlainMessage = `From: =?ISO-8859-1?Q?Andr=E9?= <andre@example.com>
+Subject: =?ISO-8859-1?Q?Caf=E9_<b>news</b>?=
+Date: Mon, 12 Oct 2026 09:00:00 +0000
+Content-Type: text/plain; charset=iso-8859-1
+Content-Transfer-Encoding: base64
+
+` + "Rmlyc3QgbGluZQpzZWNvbmQgbGluZQoKMSA8IDI=\n"
+
+func mockMaildir(t *testing.T, messages map[string]string) string {
+	dir, err := ioutil.TempDir("", "maildir")
+	assert.NoError(t, err)
+
+	for _, sub := range []string{"new", "cur", "tmp"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, sub), 0700))
+	}
+
+	for name, message := range messages {
+		message = strings.Replace(message, "\n", "\r\n", -1)
+		err = ioutil.WriteFile(filepath.Join(dir, "new", name), []byte(message), 0600)
+		assert.NoError(t, err)
+	}
+
+	return dir
+}
+
+func TestParseFeedReadsMaildir(t *testing.T) {
+	dir := mockMaildir(t, map[string]string{
+		"1.plain.host":     mockPlainMessage,
+		"2.multipart.host": mockMultipartMessage,
+	})
+	defer os.RemoveAll(dir)
+
+	p, err := New(&config.Config{
+		Feeds: []*config.FeedConfig{{URL: "maildir:" + dir, Maildir: dir}},
+	})
+	assert.NoError(t, err)
+
+	f := &feed.Feed{URL: "maildir:" + dir}
+	resp, err := p.ParseFeed(context.Background(), f)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 2)
+
+	// The newest message comes first
+	item := resp.Items[0]
+	assert.Equal(t, "Issue 42", item.Title)
+	assert.Equal(t, "Security Weekly", item.Name)
+	assert.Equal(t, "news@example.com", item.Email)
+	assert.Equal(t, "issue-42@example.com", item.GUID)
+	assert.Equal(t, time.Date(2026, time.October, 13, 9, 0, 0, 0, time.UTC), item.Published.UTC())
+	assert.Equal(t, `<p>This week&#39;s <a href="https://example.com/42">issue</a></p>`, item.Content)
+
+	item = resp.Items[1]
+	assert.Equal(t, "Café &lt;b&gt;news&lt;/b&gt;", item.Title)
+	assert.Equal(t, "André", item.Name)
+	assert.Equal(t, "1.plain.host", item.GUID)
+	assert.Equal(t, "<p>First line<br>second line</p><p>1 &lt; 2</p>", item.Content)
+
+	// The messages are only moved once committed
+	_, err = os.Stat(filepath.Join(dir, "new", "1.plain.host"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, resp.Commit(resp.Items))
+
+	for _, name := range []string{"1.plain.host:2,", "2.multipart.host:2,"} {
+		_, err = os.Stat(filepath.Join(dir, "cur", name))
+		assert.NoError(t, err)
+	}
+
+	resp, err = p.ParseFeed(context.Background(), f)
+	assert.NoError(t, err)
+	assert.True(t, resp.NotModified)
+}
+
+func TestParseFeedOnlyCommitsSavedMessages(t *testing.T) {
+	dir := mockMaildir(t, map[string]string{
+		"1.plain.host":     mockPlainMessage,
+		"2.multipart.host": mockMultipartMessage,
+	})
+	defer os.RemoveAll(dir)
+
+	p, err := New(&config.Config{
+		Feeds: []*config.FeedConfig{{URL: "maildir:" + dir, Maildir: dir}},
+	})
+	assert.NoError(t, err)
+
+	f := &feed.Feed{URL: "maildir:" + dir}
+	resp, err := p.ParseFeed(context.Background(), f)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 2)
+
+	// Only the newest message was saved, so the other is read again
+	assert.NoError(t, resp.Commit(resp.Items[:1]))
+
+	_, err = os.Stat(filepath.Join(dir, "cur", "2.multipart.host:2,"))
+	assert.NoError(t, err)
+
+	resp, err = p.ParseFeed(context.Background(), f)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 1)
+	assert.Equal(t, "1.plain.host", resp.Items[0].GUID)
+}
+
+func TestParseFeedSkipsUnreadableMessages(t *testing.T) {
+	dir := mockMaildir(t, map[string]string{
+		"1.plain.host": mockPlainMessage,
+		"2.broken.host": `From: news@example.com
+Subject: Broken
+Content-Type: multipart/alternative
+
+body`,
+	})
+	defer os.RemoveAll(dir)
+
+	p, err := New(&config.Config{
+		Feeds: []*config.FeedConfig{{URL: "maildir:" + dir, Maildir: dir}},
+	})
+	assert.NoError(t, err)
+
+	resp, err := p.ParseFeed(context.Background(), &feed.Feed{URL: "maildir:" + dir})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 1)
+	assert.Equal(t, "1.plain.host", resp.Items[0].GUID)
+
+	// Nothing is moved until the response is committed, ex. when previewing
+	_, err = os.Stat(filepath.Join(dir, "new", "2.broken.host"))
+	assert.NoError(t, err)
+
+	// The unreadable message is flagged, rather than read again
+	assert.NoError(t, resp.Commit(nil))
+
+	_, err = os.Stat(filepath.Join(dir, "cur", "2.broken.host:2,F"))
+	assert.NoError(t, err)
+}
+
+func TestParseFeedReturnsErrorWhenMaildirIsMissing(t *testing.T) {
+	p, err := New(&config.Config{
+		Feeds: []*config.FeedConfig{{URL: "maildir:/nonexistent", Maildir: "/nonexistent"}},
+	})
+	assert.NoError(t, err)
+
+	_, err = p.ParseFeed(context.Background(), &feed.Feed{URL: "maildir:/nonexistent"})
+	assert.Error(t, err)
+}
@@ -1,40 +1,317 @@
 package parser
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"gonews/config"
 	"gonews/feed"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/atom"
+	ext "github.com/mmcdole/gofeed/extensions"
 )
 
-// Parser contains the method needed to parse a list of items from a given RSS
-// URL
+// Parser contains the methods needed to parse a list of items from a given RSS
+// URL, to discover the feeds available from a website, to extract the article
+// linked by an item, and to fetch a feed's icon
+// Requests & commands are stopped when the given context is done
 type Parser interface {
-	ParseURL(string) ([]*feed.Item, error)
+	Discover(context.Context, string) ([]*Candidate, error)
+	DiscoverPublic(context.Context, string) ([]*Candidate, error)
+	FetchIcon(context.Context, *feed.Feed) (*Icon, error)
+	Parse(io.Reader, string) ([]*feed.Item, error)
+	ParseArticle(context.Context, string) (string, error)
+	ParseURL(context.Context, string) ([]*feed.Item, error)
+	ParseFeed(context.Context, *feed.Feed) (*Response, error)
+}
+
+// Response contains the result of fetching a feed
+// If the server reports that the feed hasn't changed since the validators
+// stored in the feed, NotModified is set and Items is empty
+// Commit, if set, must be called with the items which were saved, or skipped,
+// once they're saved, ex. to move their Maildir messages out of new/; items
+// which weren't passed, ex. those beyond the feed's FetchLimit, or all of them
+// if saving fails, are fetched again
+// Metadata is set if the feed describes itself, which Maildirs don't
+type Response struct {
+	Items        []*feed.Item
+	Metadata     *feed.Metadata
+	NotModified  bool
+	ETag         string
+	LastModified string
+	StatusCode   int
+	Links        Links
+	Commit       func([]*feed.Item) error
+}
+
+// HTTPError is returned when the server responds with an unsuccessful status
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (err HTTPError) Error() string {
+	return fmt.Sprintf("http error: %s", err.Status)
 }
 
 // New creates a new instance of a struct compatible with the Parser interface
-func New() (Parser, error) {
+// Feeds are fetched with the HTTP options from their config, if any, and other
+// requests with the default options; the config may be nil
+func New(cfg *config.Config) (Parser, error) {
+	feedClients := make(map[string]*client)
+	if cfg != nil {
+		for _, fc := range cfg.Feeds {
+			feedClients[fc.URL] = newClient(fc)
+		}
+	}
+
+	gp := gofeed.NewParser()
+	gp.AtomTranslator = &atomTranslator{}
+
 	return &gfParser{
-		parser: gofeed.NewParser(),
+		parser:       gp,
+		client:       newClient(nil),
+		publicClient: newPublicClient(),
+		feedClients:  feedClients,
 	}, nil
 }
 
 type gfParser struct {
-	parser *gofeed.Parser
+	parser       *gofeed.Parser
+	client       *client
+	publicClient *client
+	feedClients  map[string]*client
 }
 
-func (p *gfParser) ParseURL(feedURL string) ([]*feed.Item, error) {
-	var items []*feed.Item
+// Return the client for the feed with the given URL
+func (p *gfParser) feedClient(feedURL string) *client {
+	c, ok := p.feedClients[feedURL]
+	if !ok {
+		return p.client
+	}
+
+	return c
+}
+
+// Parse parses the items from the given RSS/Atom/JSON Feed document of the feed
+// with the given URL, which relative links are resolved against
+func (p *gfParser) Parse(r io.Reader, feedURL string) ([]*feed.Item, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+
+	gfeed, err := p.parseGofeed("", body)
+	if err != nil {
+		return nil, err
+	}
+
+	return itemsFromGofeed(gfeed, feedURL)
+}
+
+// Parse the document into a gofeed feed, detecting JSON Feeds by the given
+// content type or by sniffing
+func (p *gfParser) parseGofeed(contentType string, body []byte) (*gofeed.Feed, error) {
+	if isJSONFeed(contentType, body) {
+		jf, err := parseJSONFeed(body)
+		if err != nil {
+			return nil, err
+		}
+
+		return jf.toGofeed(), nil
+	}
+
+	gfeed, err := p.parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	return gfeed, nil
+}
+
+func (p *gfParser) ParseURL(ctx context.Context, feedURL string) ([]*feed.Item, error) {
+	resp, err := p.ParseFeed(ctx, &feed.Feed{URL: feedURL})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Items, nil
+}
+
+// ParseFeed fetches the feed, sending the cache validators from the previous
+// fetch, if any, in a conditional request
+// Feeds with a command, a Maildir or a file:// URL are read from the command's
+// output, the Maildir or the file instead
+func (p *gfParser) ParseFeed(ctx context.Context, f *feed.Feed) (*Response, error) {
+	c := p.feedClient(f.URL)
+	if len(c.command) > 0 {
+		return p.parseCommand(ctx, c, f)
+	}
+	if c.maildir != "" {
+		return p.parseMaildir(c)
+	}
+	if isFileURL(f.URL) {
+		return p.parseFile(c, f)
+	}
+
+	req, err := c.newRequest(ctx, f.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.ETag != "" {
+		req.Header.Set("If-None-Match", f.ETag)
+	}
+	if f.LastModified != "" {
+		req.Header.Set("If-Modified-Since", f.LastModified)
+	}
 
-	gfeed, err := p.parser.ParseURL(feedURL)
+	resp, err := c.http.Do(req)
 	if err != nil {
-		return items, fmt.Errorf("failed to parse feed: %w", err)
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
 	}
 
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return &Response{
+			NotModified:  true,
+			ETag:         f.ETag,
+			LastModified: f.LastModified,
+			StatusCode:   resp.StatusCode,
+		}, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to parse feed: %w", HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		})
+	}
+
+	body, err := c.readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return p.response(c, f, resp.StatusCode, resp.Header, body)
+}
+
+// Parse the fetched feed into a response, with the validators & links from
+// the given headers
+// If the client scrapes the feed, its items are scraped from the HTML page
+// instead
+func (p *gfParser) response(c *client, f *feed.Feed, statusCode int, header http.Header, body []byte) (*Response, error) {
+	var gfeed *gofeed.Feed
+	var links Links
+	var err error
+	if c.scrape != nil {
+		gfeed, err = scrape(c.scrape, f.URL, body)
+		headerLinks(header, &links)
+	} else {
+		contentType := header.Get("Content-Type")
+		gfeed, err = p.parseGofeed(contentType, body)
+		links = findLinks(header, contentType, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := itemsFromGofeed(gfeed, f.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		Items:        items,
+		Metadata:     feed.MetadataFromGofeed(gfeed, baseURL(gfeed, f.URL)),
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		StatusCode:   statusCode,
+		Links:        links,
+	}, nil
+}
+
+// atomTranslator translates Atom feeds like gofeed's default translator, but
+// also passes on all the authors of each entry as Dublin Core creators, since
+// gofeed items only have one author
+type atomTranslator struct {
+	gofeed.DefaultAtomTranslator
+}
+
+func (t *atomTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	gfeed, err := t.DefaultAtomTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+
+	// The default translator fails unless the feed is an Atom feed, and
+	// translates each entry, in order, into an item
+	afeed := feed.(*atom.Feed)
+	for i, entry := range afeed.Entries {
+		if i >= len(gfeed.Items) {
+			break
+		}
+
+		var names []string
+		for _, a := range entry.Authors {
+			if a != nil {
+				names = append(names, a.Name)
+			}
+		}
+		gfeed.Items[i].DublinCoreExt = creators(names)
+	}
+
+	return gfeed, nil
+}
+
+// Return a Dublin Core extension listing the given authors as creators, or nil
+// if there aren't several, in which case the item's author is enough
+func creators(names []string) *ext.DublinCoreExtension {
+	if len(names) < 2 {
+		return nil
+	}
+
+	return &ext.DublinCoreExtension{Creator: names}
+}
+
+// Return the URL which relative links in the feed's items are resolved
+// against: the feed's website, resolved against the URL of the feed itself,
+// or nil if neither is known
+func baseURL(gfeed *gofeed.Feed, feedURL string) *url.URL {
+	base, err := url.Parse(feedURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+
+	link, err := url.Parse(strings.TrimSpace(gfeed.Link))
+	if err != nil || gfeed.Link == "" {
+		return base
+	}
+
+	if base != nil {
+		return base.ResolveReference(link)
+	}
+	if link.IsAbs() {
+		return link
+	}
+
+	return nil
+}
+
+func itemsFromGofeed(gfeed *gofeed.Feed, feedURL string) ([]*feed.Item, error) {
+	base := baseURL(gfeed, feedURL)
+
+	var items []*feed.Item
 	for _, gitem := range gfeed.Items {
 		var i feed.Item
-		err := i.FromGofeedItem(gitem)
+		err := i.FromGofeedItem(gitem, base)
 		if err != nil {
 			return items, fmt.Errorf("failed to initialize item: %w", err)
 		}
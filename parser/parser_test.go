@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"gonews/feed"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockETag = `"abc"`
+
+func mockFeedServer(t *testing.T) *httptest.Server {
+	body, err := ioutil.ReadFile("../lib/test/sample.xml")
+	assert.NoError(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == mockETag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", mockETag)
+		w.Write(body)
+	}))
+}
+
+func TestParseFeedReturnsItemsAndValidators(t *testing.T) {
+	server := mockFeedServer(t)
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	resp, err := p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL})
+	assert.NoError(t, err)
+	assert.False(t, resp.NotModified)
+	assert.Len(t, resp.Items, 3)
+	assert.Equal(t, mockETag, resp.ETag)
+}
+
+func TestParseFeedReturnsNotModifiedWhenValidatorsMatch(t *testing.T) {
+	server := mockFeedServer(t)
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	resp, err := p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL, ETag: mockETag})
+	assert.NoError(t, err)
+	assert.True(t, resp.NotModified)
+	assert.Empty(t, resp.Items)
+	assert.Equal(t, mockETag, resp.ETag)
+}
+
+func TestParseFeedReturnsErrorOnHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	_, err = p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL})
+
+	var httpErr HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+}
+
+func TestParseFeedStopsRequestWhenContextIsDone(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = p.ParseFeed(ctx, &feed.Feed{URL: server.URL})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestParseFeedReturnsLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `<https://hub.example.com/>; rel="hub"`)
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Test</title>
+  <link rel="hub" href="https://other-hub.example.com/"/>
+  <link rel="self" href="https://example.com/feed.xml"/>
+  <entry>
+    <title>Entry</title>
+    <link rel="self" href="https://example.com/entry"/>
+  </entry>
+</feed>`))
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	resp, err := p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, Links{
+		Hub:  "https://hub.example.com/",
+		Self: "https://example.com/feed.xml",
+	}, resp.Links)
+}
+
+func TestParseFeedResolvesRelativeLinksAgainstWebsite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Test</title>
+    <link>/blog/</link>
+    <item>
+      <title>Post</title>
+      <link>https://example.com/post</link>
+      <description><![CDATA[<p>Read <a href="post">more</a></p><script>alert(1)</script>]]></description>
+    </item>
+  </channel>
+</rss>`))
+	}))
+	defer server.Close()
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	resp, err := p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL + "/feed.xml"})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 1)
+	assert.Equal(t, `<p>Read <a href="`+server.URL+`/blog/post">more</a></p>`, resp.Items[0].Description)
+	assert.Equal(t, "Test", resp.Metadata.Title)
+	assert.Equal(t, server.URL+"/blog/", resp.Metadata.Link)
+}
+
+func TestParseResolvesRelativeLinksAgainstFeedURL(t *testing.T) {
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	items, err := p.Parse(strings.NewReader(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Test</title>
+    <link>/blog/</link>
+    <item>
+      <title>Post</title>
+      <link>https://example.com/post</link>
+      <description><![CDATA[<p>Read <a href="post">more</a></p>]]></description>
+    </item>
+  </channel>
+</rss>`), "https://example.com/feed.xml")
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, `<p>Read <a href="https://example.com/blog/post">more</a></p>`, items[0].Description)
+}
+
+func TestParseKeepsExtraAtomAuthors(t *testing.T) {
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	items, err := p.Parse(strings.NewReader(`<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Test</title>
+  <entry>
+    <title>Post</title>
+    <id>1</id>
+    <author><name>Alice</name><email>alice@example.com</email></author>
+    <author><name>Bob</name></author>
+  </entry>
+  <entry>
+    <title>Other</title>
+    <id>2</id>
+    <author><name>Carol</name></author>
+  </entry>
+</feed>`), "")
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "Alice", items[0].Name)
+	assert.Equal(t, "alice@example.com", items[0].Email)
+	assert.Equal(t, []*feed.ItemAuthor{{Name: "Bob"}}, items[0].Authors)
+	assert.Equal(t, "Carol", items[1].Name)
+	assert.Empty(t, items[1].Authors)
+}
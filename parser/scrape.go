@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"gonews/config"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/mmcdole/gofeed"
+)
+
+// Compile the configured selectors, so that invalid selectors fail the feed
+// rather than silently matching nothing
+func validateSelectors(sc *config.ScrapeConfig) error {
+	if sc.Item == "" {
+		return fmt.Errorf("failed to scrape page: item selector required")
+	}
+
+	for _, selector := range []string{sc.Item, sc.Title, sc.Link, sc.Date, sc.Description} {
+		if selector == "" {
+			continue
+		}
+
+		_, err := cascadia.Compile(selector)
+		if err != nil {
+			return fmt.Errorf("failed to compile selector %q: %w", selector, err)
+		}
+	}
+
+	return nil
+}
+
+// Return the selected element within the item, or the item itself if there's
+// no selector
+func selectField(item *goquery.Selection, selector string) *goquery.Selection {
+	if selector == "" {
+		return item
+	}
+
+	return item.Find(selector).First()
+}
+
+// Return the href of the selected element, or of the first link inside it,
+// resolved against the page's URL
+func scrapeLink(s *goquery.Selection, pageURL *url.URL) string {
+	href, ok := s.Attr("href")
+	if !ok {
+		href, ok = s.Find("a[href]").First().Attr("href")
+	}
+	if !ok {
+		return ""
+	}
+
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	if pageURL != nil {
+		u = pageURL.ResolveReference(u)
+	}
+
+	return u.String()
+}
+
+// Scrape the items from the HTML page with the configured selectors, into a
+// gofeed feed, so that they're mapped onto feed items by the same rules as
+// the items of RSS & Atom feeds
+// Items without a title or link are skipped, and dates which don't match the
+// layout are left unset
+func scrape(sc *config.ScrapeConfig, pageURL string, page []byte) (*gofeed.Feed, error) {
+	err := validateSelectors(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+
+	// Links are relative to the page's <base>, if any
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		baseHref, err := url.Parse(href)
+		if err == nil && base != nil {
+			base = base.ResolveReference(baseHref)
+		}
+	}
+
+	gfeed := &gofeed.Feed{
+		Title: strings.TrimSpace(doc.Find("title").First().Text()),
+	}
+	if base != nil {
+		gfeed.Link = base.String()
+	}
+
+	doc.Find(sc.Item).Each(func(_ int, s *goquery.Selection) {
+		title := strings.TrimSpace(selectField(s, sc.Title).Text())
+		link := scrapeLink(selectField(s, sc.Link), base)
+		if title == "" || link == "" {
+			return
+		}
+
+		item := &gofeed.Item{
+			Title: title,
+			Link:  link,
+		}
+
+		if sc.Date != "" {
+			date := strings.TrimSpace(selectField(s, sc.Date).Text())
+			published, err := time.Parse(sc.DateLayout, date)
+			if err == nil {
+				item.Published = date
+				item.PublishedParsed = &published
+			}
+		}
+
+		if sc.Description != "" {
+			description, err := selectField(s, sc.Description).Html()
+			if err == nil {
+				item.Description = strings.TrimSpace(description)
+			}
+		}
+
+		gfeed.Items = append(gfeed.Items, item)
+	})
+
+	return gfeed, nil
+}
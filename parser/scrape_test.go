@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"context"
+	"gonews/config"
+	"gonews/feed"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockAdvisoriesPage = `<!DOCTYPE html>
+<html>
+<head><title>Security Advisories</title></head>
+<body>
+  <table class="advisories">
+    <tr><th>Advisory</th><th>Published</th><th>Summary</th></tr>
+    <tr>
+      <td class="title"><a href="/advisories/2">VSA-2: Remote code execution</a></td>
+      <td class="date">2026-10-02</td>
+      <td class="summary"><p>Upgrade to <a href="/releases/1.2">1.2</a>.</p><script>alert(1)</script></td>
+    </tr>
+    <tr>
+      <td class="title"><a href="https://example.com/advisories/1">VSA-1: Denial of service</a></td>
+      <td class="date">unknown</td>
+      <td class="summary">No fix yet.</td>
+    </tr>
+  </table>
+</body>
+</html>`
+
+func scrapeConfig(url string) *config.Config {
+	return &config.Config{
+		Feeds: []*config.FeedConfig{
+			{
+				URL: url,
+				Scrape: &config.ScrapeConfig{
+					Item:        "table.advisories tr",
+					Title:       "td.title",
+					Link:        "td.title a",
+					Date:        "td.date",
+					DateLayout:  "2006-01-02",
+					Description: "td.summary",
+				},
+			},
+		},
+	}
+}
+
+func TestParseFeedScrapesItems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(mockAdvisoriesPage))
+	}))
+	defer server.Close()
+
+	pageURL := server.URL + "/advisories/"
+	p, err := New(scrapeConfig(pageURL))
+	assert.NoError(t, err)
+
+	resp, err := p.ParseFeed(context.Background(), &feed.Feed{URL: pageURL})
+	assert.NoError(t, err)
+
+	// The header row has no title or link, so it's skipped
+	assert.Len(t, resp.Items, 2)
+
+	assert.Equal(t, "VSA-2: Remote code execution", resp.Items[0].Title)
+	assert.Equal(t, server.URL+"/advisories/2", resp.Items[0].Link)
+	assert.Equal(t, time.Date(2026, time.October, 2, 0, 0, 0, 0, time.UTC), resp.Items[0].Published)
+	assert.Equal(t, `<p>Upgrade to <a href="`+server.URL+`/releases/1.2">1.2</a>.</p>`, resp.Items[0].Description)
+
+	assert.Equal(t, "VSA-1: Denial of service", resp.Items[1].Title)
+	assert.Equal(t, "https://example.com/advisories/1", resp.Items[1].Link)
+	assert.True(t, resp.Items[1].Published.IsZero())
+	assert.Equal(t, "No fix yet.", resp.Items[1].Description)
+}
+
+func TestParseFeedReturnsErrorOnInvalidSelector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mockAdvisoriesPage))
+	}))
+	defer server.Close()
+
+	cfg := scrapeConfig(server.URL)
+	cfg.Feeds[0].Scrape.Title = "td[title"
+
+	p, err := New(cfg)
+	assert.NoError(t, err)
+
+	_, err = p.ParseFeed(context.Background(), &feed.Feed{URL: server.URL})
+	assert.Error(t, err)
+}
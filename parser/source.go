@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"gonews/feed"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Maximum length of a command's stderr included in its error
+const maxStderrLength = 1024
+
+// CommandError is returned when a feed's command exits unsuccessfully
+type CommandError struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (err CommandError) Error() string {
+	if err.Stderr == "" {
+		return fmt.Sprintf("command exited with status %d", err.ExitCode)
+	}
+
+	return fmt.Sprintf("command exited with status %d: %s", err.ExitCode, err.Stderr)
+}
+
+func isFileURL(feedURL string) bool {
+	return strings.HasPrefix(strings.ToLower(feedURL), "file://")
+}
+
+// Read the feed from the file at its file:// URL
+// The file's modification time is used as the Last-Modified validator, so that
+// unchanged files are reported as not modified
+func (p *gfParser) parseFile(c *client, f *feed.Feed) (*Response, error) {
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file URL: %w", err)
+	}
+
+	file, err := os.Open(u.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feed file: %w", err)
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat feed file: %w", err)
+	}
+
+	lastModified := info.ModTime().UTC().Format(http.TimeFormat)
+	if f.LastModified == lastModified {
+		return &Response{
+			NotModified:  true,
+			LastModified: lastModified,
+		}, nil
+	}
+
+	body, err := c.readBody(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed file: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("Last-Modified", lastModified)
+
+	return p.response(c, f, 0, header, body)
+}
+
+// Return the last n bytes read from the reader, without surrounding space
+func readTail(r io.Reader, n int) string {
+	var tail []byte
+	buf := make([]byte, 4096)
+	for {
+		read, err := r.Read(buf)
+		tail = append(tail, buf[:read]...)
+		if len(tail) > n {
+			tail = tail[len(tail)-n:]
+		}
+
+		if err != nil {
+			return strings.TrimSpace(string(tail))
+		}
+	}
+}
+
+// Run the feed's command, and read the feed from its stdout
+// The command is killed if it runs longer than the feed's timeout, or if the
+// given context is done first, and fails the feed if it exits unsuccessfully
+func (p *gfParser) parseCommand(parent context.Context, c *client, f *feed.Feed) (*Response, error) {
+	ctx, cancel := context.WithTimeout(parent, c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create command pipe: %w", err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create command pipe: %w", err)
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	// Processes started by the command may keep the pipes open after it's
+	// killed, so they're closed to stop reading
+	go func() {
+		<-ctx.Done()
+		stdout.Close()
+		stderr.Close()
+	}()
+
+	stderrTail := make(chan string, 1)
+	go func() {
+		stderrTail <- readTail(stderr, maxStderrLength)
+	}()
+
+	body, readErr := c.readBody(stdout)
+	if readErr != nil {
+		// Kill the command rather than wait for it to fill the pipe
+		cancel()
+	}
+
+	msg := <-stderrTail
+	err = cmd.Wait()
+	if parent.Err() != nil {
+		return nil, fmt.Errorf("failed to run command: %w", parent.Err())
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("command timed out after %s", c.timeout)
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read command output: %w", readErr)
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("failed to run command: %w", CommandError{
+			ExitCode: exitErr.ExitCode(),
+			Stderr:   msg,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command: %w", err)
+	}
+
+	return p.response(c, f, 0, http.Header{}, body)
+}
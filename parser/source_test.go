@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"gonews/config"
+	"gonews/feed"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFeedReadsFile(t *testing.T) {
+	path, err := filepath.Abs("../lib/test/sample.xml")
+	assert.NoError(t, err)
+
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	f := &feed.Feed{URL: "file://" + filepath.ToSlash(path)}
+	resp, err := p.ParseFeed(context.Background(), f)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.Items)
+	assert.NotEmpty(t, resp.LastModified)
+
+	// The file hasn't changed since it was last read
+	f.LastModified = resp.LastModified
+	resp, err = p.ParseFeed(context.Background(), f)
+	assert.NoError(t, err)
+	assert.True(t, resp.NotModified)
+	assert.Empty(t, resp.Items)
+}
+
+func TestParseFeedReturnsErrorWhenFileIsMissing(t *testing.T) {
+	p, err := New(nil)
+	assert.NoError(t, err)
+
+	_, err = p.ParseFeed(context.Background(), &feed.Feed{URL: "file:///nonexistent/feed.xml"})
+	assert.Error(t, err)
+}
+
+func commandParser(t *testing.T, url string, timeout time.Duration, command ...string) Parser {
+	p, err := New(&config.Config{
+		Feeds: []*config.FeedConfig{{URL: url, Command: command, Timeout: timeout}},
+	})
+	assert.NoError(t, err)
+
+	return p
+}
+
+func TestParseFeedRunsCommand(t *testing.T) {
+	p := commandParser(t, "command:cat", 0, "cat", "../lib/test/sample.xml")
+
+	resp, err := p.ParseFeed(context.Background(), &feed.Feed{URL: "command:cat"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.Items)
+}
+
+func TestParseFeedReturnsErrorWhenCommandFails(t *testing.T) {
+	p := commandParser(t, "command:fail", 0, "sh", "-c", "echo oops >&2; exit 3")
+
+	_, err := p.ParseFeed(context.Background(), &feed.Feed{URL: "command:fail"})
+	var cmdErr CommandError
+	assert.True(t, errors.As(err, &cmdErr))
+	assert.Equal(t, 3, cmdErr.ExitCode)
+	assert.Equal(t, "oops", cmdErr.Stderr)
+}
+
+func TestParseFeedReturnsErrorWhenCommandTimesOut(t *testing.T) {
+	// The shell starts sleep as a child process, which isn't killed along
+	// with it
+	p := commandParser(t, "command:sleep", 50*time.Millisecond, "sh", "-c", "sleep 5; echo")
+
+	start := time.Now()
+	_, err := p.ParseFeed(context.Background(), &feed.Feed{URL: "command:sleep"})
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestParseFeedStopsCommandWhenContextIsDone(t *testing.T) {
+	p := commandParser(t, "command:sleep", time.Minute, "sh", "-c", "sleep 5; echo")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.ParseFeed(ctx, &feed.Feed{URL: "command:sleep"})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestParseFeedReturnsErrorWhenCommandOutputTooLarge(t *testing.T) {
+	p, err := New(&config.Config{
+		Feeds: []*config.FeedConfig{
+			{URL: "command:yes", Command: []string{"yes"}, MaxBodyBytes: 1024},
+		},
+	})
+	assert.NoError(t, err)
+
+	_, err = p.ParseFeed(context.Background(), &feed.Feed{URL: "command:yes"})
+	assert.True(t, errors.Is(err, ErrBodyTooLarge))
+}
@@ -0,0 +1,142 @@
+package websub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gonews/db"
+	"gonews/db/orm/query"
+	"gonews/db/orm/query/clause"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Upper bound on the size of a content distribution request
+const maxContentBytes = 10 << 20
+
+// ContentFunc is called with the body of each content distribution request
+// whose signature is valid, and the request's context
+type ContentFunc func(ctx context.Context, db db.DB, sub *Subscription, body []byte) error
+
+// Handler handles the requests sent by hubs to subscription callback URLs,
+// which end with the subscription's token
+// GET requests verify the intent to subscribe, and POST requests deliver
+// content
+type Handler struct {
+	db        db.DB
+	onContent ContentFunc
+}
+
+// NewHandler creates a Handler which looks up subscriptions in the given DB
+// and passes delivered content to the given function
+func NewHandler(db db.DB, onContent ContentFunc) *Handler {
+	return &Handler{
+		db:        db,
+		onContent: onContent,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := path.Base(r.URL.Path)
+	if token == "" || token == "/" || token == "." {
+		http.NotFound(w, r)
+		return
+	}
+
+	var sub Subscription
+	err := h.db.FindContext(r.Context(), &sub, clause.Where("token = ?", token))
+	if errors.Is(err, query.ErrModelNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msg("Failed to get matching subscription")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.verify(w, r, h.db, &sub)
+	case http.MethodPost:
+		h.receive(w, r, h.db, &sub)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Respond to the hub's verification of intent
+// Verifications & denials are only accepted while the subscription is pending,
+// since the hub only sends them in response to a request
+func (h *Handler) verify(w http.ResponseWriter, r *http.Request, db db.DB, sub *Subscription) {
+	params := r.URL.Query()
+	if params.Get("hub.topic") != sub.Topic || !sub.Pending() {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch params.Get("hub.mode") {
+	case "subscribe":
+		lease, err := strconv.ParseUint(params.Get("hub.lease_seconds"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		sub.ExpiresAt = time.Now().Add(time.Duration(lease) * time.Second)
+	case "denied":
+		log.Warn().Msgf("subscription denied by hub: %s, reason: %s", sub, params.Get("hub.reason"))
+		sub.ExpiresAt = time.Time{}
+	default:
+		// Subscriptions are never explicitly removed, so any other
+		// request wasn't made by us
+		http.NotFound(w, r)
+		return
+	}
+
+	err := db.SaveContext(r.Context(), sub)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to save subscription")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	log.Info().Msgf("verified: %s", sub)
+
+	_, err = w.Write([]byte(params.Get("hub.challenge")))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to write response")
+	}
+}
+
+// Pass the delivered content to the content function, if it was signed
+// with the subscription's secret
+func (h *Handler) receive(w http.ResponseWriter, r *http.Request, db db.DB, sub *Subscription) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxContentBytes))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read content")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Per the spec, invalid content is acknowledged but ignored, so as not
+	// to reveal whether the signature was valid
+	if !VerifySignature(sub.Secret, body, r.Header.Get("X-Hub-Signature")) {
+		log.Warn().Msgf("ignoring content with invalid signature: %s", sub)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	err = h.onContent(r.Context(), db, sub, body)
+	if err != nil {
+		log.Error().Err(fmt.Errorf("failed to handle content: %w", err)).Msgf("Failed to receive content for %s", sub)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,163 @@
+package websub
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- sha1 signatures are still sent by most hubs
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hubs' verifications & denials of a subscription are only accepted for this
+// long after it's requested
+const VerifyPeriod = time.Hour
+
+// Subscription contains the state of a WebSub subscription to the hub of a
+// feed, stored in the database
+// Token identifies the subscription in its callback URL, and is random so that
+// the URL can't be guessed
+// RequestedAt is when the subscription was last requested from the hub, and
+// ExpiresAt is when the lease granted by the hub runs out; it's zero until the
+// hub verifies the subscription
+type Subscription struct {
+	ID          uint
+	FeedID      uint
+	Hub         string
+	Topic       string
+	Secret      string
+	RequestedAt time.Time
+	ExpiresAt   time.Time
+	Token       string
+}
+
+func (s Subscription) String() string {
+	return fmt.Sprintf(
+		"Subscription{FeedID: %d, Hub: %s, Topic: %s, RequestedAt: %s, ExpiresAt: %s}",
+		s.FeedID,
+		s.Hub,
+		s.Topic,
+		s.RequestedAt,
+		s.ExpiresAt)
+}
+
+// Pending returns true if the subscription was requested from the hub recently
+// enough for the hub to still be verifying it
+func (s Subscription) Pending() bool {
+	return !s.RequestedAt.IsZero() && time.Since(s.RequestedAt) < VerifyPeriod
+}
+
+// Active returns true if the hub has verified the subscription and its lease
+// hasn't expired
+func (s Subscription) Active() bool {
+	return time.Now().Before(s.ExpiresAt)
+}
+
+// CallbackURL returns the URL which the hub should deliver content to, given
+// the base callback URL of the app
+func (s Subscription) CallbackURL(base string) string {
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(base, "/"), s.Token)
+}
+
+// Generate a random hex string
+func randomHex() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// NewSecret generates a random secret, used by the hub to sign content
+func NewSecret() (string, error) {
+	secret, err := randomHex()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// NewToken generates a random token, which identifies a subscription in its
+// callback URL
+func NewToken() (string, error) {
+	token, err := randomHex()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return token, nil
+}
+
+// Subscribe asks the hub to deliver the subscription's topic to the given
+// callback URL
+// The hub verifies the request asynchronously, by calling back with a
+// challenge; see Handler
+// If lease is zero, the hub chooses the lease duration
+func Subscribe(client *http.Client, sub *Subscription, callbackURL string, lease time.Duration) error {
+	form := url.Values{}
+	form.Set("hub.mode", "subscribe")
+	form.Set("hub.topic", sub.Topic)
+	form.Set("hub.callback", callbackURL)
+	if sub.Secret != "" {
+		form.Set("hub.secret", sub.Secret)
+	}
+	if lease > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(int(lease.Seconds())))
+	}
+
+	resp, err := client.PostForm(sub.Hub, form)
+	if err != nil {
+		return fmt.Errorf("failed to send subscription request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("subscription request rejected: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// VerifySignature returns true if the given X-Hub-Signature header value,
+// ex. "sha256=<hex digest>", is a valid HMAC of the body with the secret
+func VerifySignature(secret string, body []byte, signature string) bool {
+	parts := strings.SplitN(signature, "=", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	var h func() hash.Hash
+	switch parts[0] {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+
+	actual, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), actual)
+}
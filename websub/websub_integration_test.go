@@ -0,0 +1,189 @@
+package websub_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gonews/db"
+	"gonews/db/orm/query/clause"
+	"gonews/test"
+	"gonews/websub"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	migrationsDir = "../db/migrations"
+)
+
+// In-process hub which verifies each subscription synchronously and
+// publishes content to its subscribers
+type mockHub struct {
+	t         *testing.T
+	callback  string
+	secret    string
+	verified  bool
+	challenge string
+}
+
+func (h *mockHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	assert.NoError(h.t, r.ParseForm())
+	assert.Equal(h.t, "subscribe", r.PostForm.Get("hub.mode"))
+
+	h.callback = r.PostForm.Get("hub.callback")
+	h.secret = r.PostForm.Get("hub.secret")
+
+	params := url.Values{}
+	params.Set("hub.mode", "subscribe")
+	params.Set("hub.topic", r.PostForm.Get("hub.topic"))
+	params.Set("hub.challenge", h.challenge)
+	params.Set("hub.lease_seconds", "3600")
+
+	resp, err := http.Get(h.callback + "?" + params.Encode())
+	assert.NoError(h.t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(h.t, err)
+
+	h.verified = resp.StatusCode == http.StatusOK && string(body) == h.challenge
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *mockHub) publish(content []byte, secret string) *http.Response {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(content)
+
+	req, err := http.NewRequest("POST", h.callback, bytes.NewReader(content))
+	assert.NoError(h.t, err)
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(h.t, err)
+	resp.Body.Close()
+
+	return resp
+}
+
+func TestSubscribe(t *testing.T) {
+	_, adb := test.InitDB(t, migrationsDir)
+	defer adb.Close()
+
+	var received [][]byte
+	onContent := func(ctx context.Context, db db.DB, sub *websub.Subscription, body []byte) error {
+		received = append(received, body)
+		return nil
+	}
+
+	subscriber := httptest.NewServer(websub.NewHandler(adb, onContent))
+	defer subscriber.Close()
+
+	hub := &mockHub{t: t, challenge: "mock_challenge"}
+	hubServer := httptest.NewServer(hub)
+	defer hubServer.Close()
+
+	secret, err := websub.NewSecret()
+	assert.NoError(t, err)
+
+	token, err := websub.NewToken()
+	assert.NoError(t, err)
+
+	sub := &websub.Subscription{
+		FeedID:      1,
+		Hub:         hubServer.URL,
+		Topic:       "https://example.com/feed.xml",
+		Secret:      secret,
+		Token:       token,
+		RequestedAt: time.Now(),
+	}
+	assert.NoError(t, adb.Save(sub))
+
+	callbackURL := sub.CallbackURL(fmt.Sprintf("%s/websub/", subscriber.URL))
+	assert.Equal(t, fmt.Sprintf("%s/websub/%s", subscriber.URL, token), callbackURL)
+
+	err = websub.Subscribe(http.DefaultClient, sub, callbackURL, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, hub.verified)
+	assert.Equal(t, secret, hub.secret)
+
+	var verifiedSub websub.Subscription
+	assert.NoError(t, adb.Find(&verifiedSub, clause.Where("id = ?", sub.ID)))
+	assert.True(t, verifiedSub.Active())
+
+	resp := hub.publish([]byte("content"), secret)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, [][]byte{[]byte("content")}, received)
+
+	// Content with an invalid signature is acknowledged, but ignored
+	resp = hub.publish([]byte("forged"), "wrong_secret")
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Len(t, received, 1)
+}
+
+func TestVerifyRejectsUnknownTopic(t *testing.T) {
+	_, adb := test.InitDB(t, migrationsDir)
+	defer adb.Close()
+
+	sub := &websub.Subscription{
+		FeedID:      1,
+		Hub:         "https://hub.example.com/",
+		Topic:       "https://example.com/feed.xml",
+		Token:       "mock_token",
+		RequestedAt: time.Now(),
+	}
+	assert.NoError(t, adb.Save(sub))
+
+	handler := websub.NewHandler(adb, nil)
+
+	req := httptest.NewRequest(
+		"GET",
+		"http://example.com/websub/mock_token?hub.mode=subscribe&hub.topic=other&hub.challenge=c&hub.lease_seconds=60",
+		nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("http://example.com/websub/%d", sub.ID), nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestVerifyIgnoresDenialWithoutPendingRequest(t *testing.T) {
+	_, adb := test.InitDB(t, migrationsDir)
+	defer adb.Close()
+
+	expiresAt := time.Now().Add(time.Hour)
+	sub := &websub.Subscription{
+		FeedID:      1,
+		Hub:         "https://hub.example.com/",
+		Topic:       "https://example.com/feed.xml",
+		Token:       "mock_token",
+		RequestedAt: time.Now().Add(-2 * websub.VerifyPeriod),
+		ExpiresAt:   expiresAt,
+	}
+	assert.NoError(t, adb.Save(sub))
+
+	handler := websub.NewHandler(adb, nil)
+
+	params := url.Values{}
+	params.Set("hub.mode", "denied")
+	params.Set("hub.topic", sub.Topic)
+	req := httptest.NewRequest("GET", "http://example.com/websub/mock_token?"+params.Encode(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var unchangedSub websub.Subscription
+	assert.NoError(t, adb.Find(&unchangedSub, clause.Where("id = ?", sub.ID)))
+	assert.True(t, unchangedSub.Active())
+}
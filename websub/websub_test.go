@@ -0,0 +1,37 @@
+package websub
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte("content")
+
+	mac := hmac.New(sha1.New, []byte("secret"))
+	mac.Write(body)
+	sha1Sig := "sha1=" + hex.EncodeToString(mac.Sum(nil))
+
+	mac = hmac.New(sha512.New, []byte("secret"))
+	mac.Write(body)
+	sha512Sig := "sha512=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, VerifySignature("secret", body, sha1Sig))
+	assert.True(t, VerifySignature("secret", body, sha512Sig))
+	assert.False(t, VerifySignature("other", body, sha1Sig))
+	assert.False(t, VerifySignature("secret", []byte("other"), sha1Sig))
+	assert.False(t, VerifySignature("secret", body, "md5=abc"))
+	assert.False(t, VerifySignature("secret", body, ""))
+}
+
+func TestPending(t *testing.T) {
+	assert.False(t, Subscription{}.Pending())
+	assert.True(t, Subscription{RequestedAt: time.Now()}.Pending())
+	assert.False(t, Subscription{RequestedAt: time.Now().Add(-2 * VerifyPeriod)}.Pending())
+}